@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/mmcloughlin/globe"
 )
@@ -49,6 +50,20 @@ func NewPointFromCity(city string) Point {
 	return Point{City: cities[city]}
 }
 
+// Tier returns the class of given city, that is 1 for first-class cores, 2
+// for second-class influxes, 3 for third-class capitals and 4 for the rest.
+func Tier(city string) int {
+	switch {
+	case firstClassCores[city]:
+		return 1
+	case secondClassInfluxes[city]:
+		return 2
+	case capitals[city]:
+		return 3
+	}
+	return 4
+}
+
 // Isolate returns a value of isolation of two points.
 func Isolate(a, b Point) float64 {
 	aX := float64(a.City.ID-minCityID) / float64(maxCityID-minCityID)
@@ -61,16 +76,29 @@ func Isolate(a, b Point) float64 {
 	return math.Sqrt(diffX*diffX + diffY*diffY)
 }
 
-// Affinity contains affinity values between two points in each direction.
-type Affinity []struct {
+// Profile contains the quality values of a link.
+type Profile struct {
+	// PacketLoss is the packet loss in percent.
+	PacketLoss int
+	// Latency is the round-trip delay.
+	Latency time.Duration
+	// Bandwidth is the bandwidth in kbit/s, 0 means unlimited.
+	Bandwidth int
+}
+
+// Link is a directed link between two points.
+type Link struct {
 	// A is the start point.
 	A Point
 	// B is the end point.
 	B Point
-	// PacketLoss is the packet loss in percent.
-	PacketLoss int
+
+	Profile
 }
 
+// Affinity contains affinity values between two points in each direction.
+type Affinity []Link
+
 // Draw renders a graph at given png file.
 func (graph Affinity) Draw(png string, side int) error {
 	g := globe.New()
@@ -152,17 +180,12 @@ func NewAffinity(points []Point) Affinity {
 				reachable = baseISPs[int(b.ISP)]
 			}
 
-			var z struct {
-				A          Point
-				B          Point
-				PacketLoss int
-			}
-
-			z.A = a
-			z.B = b
+			z := Link{A: a, B: b}
 			z.PacketLoss = 100
 			if reachable {
-				z.PacketLoss = int(Isolate(a, b) * 100)
+				isolation := Isolate(a, b)
+				z.PacketLoss = int(isolation * 100)
+				z.Latency = time.Duration(isolation * float64(maxLatency))
 			}
 			r = append(r, z)
 		}
@@ -170,10 +193,14 @@ func NewAffinity(points []Point) Affinity {
 	return r
 }
 
+// maxLatency is the latency between two completely isolated points.
+const maxLatency = 200 * time.Millisecond
+
 var (
 	cities               = make(map[string]City)
 	minCityID, maxCityID int
 	names                []string
+	capitals             = make(map[string]bool)
 	firstClassCores      = map[string]bool{
 		"北京市": true,
 		"上海市": true,
@@ -245,6 +272,10 @@ func init() {
 
 			cities[city.Name] = city
 			names = append(names, city.Name)
+			if i == 0 {
+				// the capital is always listed first
+				capitals[city.Name] = true
+			}
 		}
 		sort.Strings(names)
 	}
@@ -0,0 +1,200 @@
+package simnet
+
+import "sort"
+
+// Base is a set of ISPs that every ISP in every province can reach at least
+// one of them.
+type Base struct {
+	ISPs []ISP
+	// Provinces maps each ISP of base to the provinces forcing it into base,
+	// where some ISP reaches no other ISPs of base.
+	Provinces map[ISP][]string
+}
+
+// cell is an ISP within a province.
+type cell struct {
+	Province string
+	ISP      ISP
+}
+
+// MinimalBase computes the minimal base of ISPs by the affinity, an ISP
+// reaches another within a province if there is a reachable link between
+// them, and an ISP in base trivially reaches itself.
+//
+// It's a set cover problem, which is solved exactly by branch and bound from
+// a greedy solution, so it might be slow on large numbers of ISPs.
+func MinimalBase(graph Affinity) Base {
+	covers := make(map[ISP]map[cell]bool)
+	cover := func(isp ISP, c cell) {
+		if covers[isp] == nil {
+			covers[isp] = make(map[cell]bool)
+		}
+		covers[isp][c] = true
+	}
+	for _, z := range graph {
+		cover(z.A.ISP, cell{z.A.City.Province, z.A.ISP})
+		cover(z.B.ISP, cell{z.B.City.Province, z.B.ISP})
+		if z.PacketLoss < 100 && z.A.City.Province == z.B.City.Province {
+			cover(z.B.ISP, cell{z.A.City.Province, z.A.ISP})
+		}
+	}
+
+	var isps []ISP
+	for isp := range covers {
+		isps = append(isps, isp)
+	}
+	sort.Slice(isps, func(i, j int) bool { return isps[i] < isps[j] })
+
+	cells := make(map[cell][]ISP)
+	for _, isp := range isps {
+		for c := range covers[isp] {
+			cells[c] = append(cells[c], isp)
+		}
+	}
+
+	// an ISP is forced if it's the only one covering a cell
+	var forced []ISP
+	chosen := make(map[ISP]bool)
+	for _, coverers := range cells {
+		if len(coverers) == 1 && !chosen[coverers[0]] {
+			chosen[coverers[0]] = true
+			forced = append(forced, coverers[0])
+		}
+	}
+
+	s := newBaseSolver(isps, cells, chosen)
+	s.best = greedyBase(s)
+	s.search(nil)
+
+	base := Base{ISPs: append(forced, s.best...), Provinces: make(map[ISP][]string)}
+	sort.Slice(base.ISPs, func(i, j int) bool { return base.ISPs[i] < base.ISPs[j] })
+	in := make(map[ISP]bool)
+	for _, isp := range base.ISPs {
+		in[isp] = true
+	}
+	for c, coverers := range cells {
+		var only []ISP
+		for _, isp := range coverers {
+			if in[isp] {
+				only = append(only, isp)
+			}
+		}
+		if len(only) == 1 {
+			base.Provinces[only[0]] = appendUnique(base.Provinces[only[0]], c.Province)
+		}
+	}
+	for _, provinces := range base.Provinces {
+		sort.Strings(provinces)
+	}
+	return base
+}
+
+// baseSolver solves the set cover of cells not covered by forced ISPs, where
+// ISPs and cells are indexed.
+type baseSolver struct {
+	isps    []ISP
+	covers  [][]int
+	cells   [][]int
+	covered []int
+	best    []ISP
+}
+
+func newBaseSolver(isps []ISP, cells map[cell][]ISP, forced map[ISP]bool) *baseSolver {
+	s := &baseSolver{isps: isps, covers: make([][]int, len(isps))}
+	index := make(map[ISP]int, len(isps))
+	for i, isp := range isps {
+		index[isp] = i
+	}
+
+	var keys []cell
+	for c, coverers := range cells {
+		covered := false
+		for _, isp := range coverers {
+			covered = covered || forced[isp]
+		}
+		if !covered {
+			keys = append(keys, c)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Province < keys[j].Province || keys[i].Province == keys[j].Province && keys[i].ISP < keys[j].ISP
+	})
+
+	for i, c := range keys {
+		var coverers []int
+		for _, isp := range cells[c] {
+			coverers = append(coverers, index[isp])
+			s.covers[index[isp]] = append(s.covers[index[isp]], i)
+		}
+		s.cells = append(s.cells, coverers)
+	}
+	s.covered = make([]int, len(s.cells))
+	return s
+}
+
+// greedyBase repeatedly chooses the ISP covering most uncovered cells.
+func greedyBase(s *baseSolver) []ISP {
+	var base []ISP
+	covered := make([]bool, len(s.cells))
+	for n := 0; n < len(s.cells); {
+		best, max := 0, -1
+		for i, cs := range s.covers {
+			count := 0
+			for _, c := range cs {
+				if !covered[c] {
+					count++
+				}
+			}
+			if count > max {
+				best, max = i, count
+			}
+		}
+
+		base = append(base, s.isps[best])
+		for _, c := range s.covers[best] {
+			if !covered[c] {
+				covered[c] = true
+				n++
+			}
+		}
+	}
+	return base
+}
+
+// search branches on ISPs covering the uncovered cell with fewest choices.
+func (s *baseSolver) search(chosen []ISP) {
+	next := -1
+	for c, coverers := range s.cells {
+		if s.covered[c] == 0 && (next < 0 || len(coverers) < len(s.cells[next])) {
+			next = c
+		}
+	}
+	if next < 0 {
+		if len(chosen) < len(s.best) {
+			s.best = append([]ISP(nil), chosen...)
+		}
+		return
+	}
+	if len(chosen)+1 >= len(s.best) {
+		return
+	}
+
+	for _, i := range s.cells[next] {
+		for _, c := range s.covers[i] {
+			s.covered[c]++
+		}
+		s.search(append(chosen, s.isps[i]))
+		for _, c := range s.covers[i] {
+			s.covered[c]--
+		}
+	}
+}
+
+func appendUnique(s []string, v string) []string {
+	for _, x := range s {
+		if x == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
@@ -0,0 +1,59 @@
+package simnet
+
+import "testing"
+
+func TestMinimalBase(t *testing.T) {
+	t.Run("Forced", func(t *testing.T) {
+		at := func(city string, isp ISP) Point { return Point{City: cities[city], ISP: isp} }
+		graph := Affinity{
+			// every ISP of 广东省 reaches 1
+			{A: at("广州市", 2), B: at("深圳市", 1)},
+			{A: at("广州市", 3), B: at("广州市", 1)},
+			// 2 of 浙江省 reaches 3 only
+			{A: at("杭州市", 2), B: at("宁波市", 3)},
+			{A: at("杭州市", 2), B: at("宁波市", 1), Profile: Profile{PacketLoss: 100}},
+			// 4 of 云南省 reaches nothing
+			{A: at("昆明市", 4), B: at("昆明市", 1), Profile: Profile{PacketLoss: 100}},
+		}
+
+		base := MinimalBase(graph)
+		if len(base.ISPs) != 3 || base.ISPs[0] != 1 || base.ISPs[1] != 3 || base.ISPs[2] != 4 {
+			t.Fatalf("expected base [1 3 4], got %v", base.ISPs)
+		}
+		if p := base.Provinces[3]; len(p) != 1 || p[0] != "浙江省" {
+			t.Errorf("expected 3 forced by 浙江省, got %v", p)
+		}
+		if p := base.Provinces[4]; len(p) != 1 || p[0] != "云南省" {
+			t.Errorf("expected 4 forced by 云南省, got %v", p)
+		}
+	})
+
+	t.Run("Model", func(t *testing.T) {
+		var points []Point
+		for i := 0; i < len(names); i++ {
+			points = append(points, NewPoint(i))
+		}
+		graph := NewAffinity(points)
+		base := MinimalBase(graph)
+
+		in := make(map[ISP]bool)
+		for _, isp := range base.ISPs {
+			in[isp] = true
+		}
+		reaches := make(map[cell]bool)
+		for _, z := range graph {
+			if in[z.A.ISP] {
+				reaches[cell{z.A.City.Province, z.A.ISP}] = true
+			}
+			if z.PacketLoss < 100 && z.A.City.Province == z.B.City.Province && in[z.B.ISP] {
+				reaches[cell{z.A.City.Province, z.A.ISP}] = true
+			}
+		}
+		for _, z := range graph {
+			if c := (cell{z.A.City.Province, z.A.ISP}); !reaches[c] {
+				t.Errorf("expected %v reaching base %v", c, base.ISPs)
+			}
+		}
+		t.Logf("base of %v ISPs: %v", len(isps), base.ISPs)
+	})
+}
@@ -0,0 +1,256 @@
+package simnet
+
+import (
+	"math"
+	"sort"
+)
+
+// TrafficMatrix contains demands in kbit/s keyed by ordered pairs of points.
+type TrafficMatrix map[Pair]float64
+
+// LinkLoad is the load of a link.
+type LinkLoad struct {
+	Pair
+	// Load is the routed traffic in kbit/s.
+	Load float64
+	// Capacity is the bandwidth of link in kbit/s, 0 means unlimited.
+	Capacity float64
+}
+
+// Utilization returns the ratio of load to capacity, 0 if unlimited.
+func (l LinkLoad) Utilization() float64 {
+	if l.Capacity <= 0 {
+		return 0
+	}
+	return l.Load / l.Capacity
+}
+
+// IDCLoad is the load of a point.
+type IDCLoad struct {
+	Point Point
+	// Load is the traffic in kbit/s sent, received or relayed by the point.
+	Load float64
+	// Relayed is the traffic in kbit/s relayed by the point.
+	Relayed float64
+	// Capacity is the capacity in kbit/s, 0 means unlimited.
+	Capacity float64
+}
+
+// Utilization returns the ratio of load to capacity, 0 if unlimited.
+func (l IDCLoad) Utilization() float64 {
+	if l.Capacity <= 0 {
+		return 0
+	}
+	return l.Load / l.Capacity
+}
+
+// CapacityReport is the result of routing a traffic matrix on a graph with
+// capacities.
+type CapacityReport struct {
+	// Links are loads of links carrying traffic in descending order of
+	// utilization.
+	Links []LinkLoad
+	// IDCs are loads of points carrying traffic in descending order of
+	// utilization.
+	IDCs []IDCLoad
+	// Unrouted are pairs of demands without routes.
+	Unrouted []Pair
+	// Demands are total demands to each destination.
+	Demands map[Point]float64
+	// MaxFlows are maximum flows to each destination from its sources, where
+	// each source sends no more than its demand, regardless of routes.
+	MaxFlows map[Point]float64
+}
+
+// Bottlenecks returns links loaded over their capacities.
+func (r *CapacityReport) Bottlenecks() []LinkLoad {
+	var links []LinkLoad
+	for _, l := range r.Links {
+		if l.Utilization() > 1 {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// Overloaded returns points loaded over their capacities, which are usually
+// relays.
+func (r *CapacityReport) Overloaded() []IDCLoad {
+	var idcs []IDCLoad
+	for _, l := range r.IDCs {
+		if l.Utilization() > 1 {
+			idcs = append(idcs, l)
+		}
+	}
+	return idcs
+}
+
+// Feasible reports whether the demands to each destination are satisfiable
+// alone, which is necessary but not sufficient for the whole matrix since
+// destinations share capacities.
+func (r *CapacityReport) Feasible() bool {
+	for p, d := range r.Demands {
+		if r.MaxFlows[p] < d*(1-1e-9) {
+			return false
+		}
+	}
+	return true
+}
+
+// PlanCapacity routes the traffic matrix on the graph by the policy, where
+// links are limited by bandwidths and points are limited by capacities in
+// kbit/s of given map, it reports loads and maximum flows to destinations.
+func PlanCapacity(g *Graph, idcs map[Point]float64, policy Policy, m TrafficMatrix) *CapacityReport {
+	r := &CapacityReport{Demands: make(map[Point]float64), MaxFlows: make(map[Point]float64)}
+
+	var pairs []Pair
+	for k := range m {
+		pairs = append(pairs, k)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return lessPair(pairs[i], pairs[j]) })
+
+	links := make(map[Pair]float64)
+	loads := make(map[Point]*IDCLoad)
+	load := func(p Point) *IDCLoad {
+		if loads[p] == nil {
+			loads[p] = &IDCLoad{Point: p, Capacity: idcs[p]}
+		}
+		return loads[p]
+	}
+	routes := make(map[Point]map[Point]Path)
+	sources := make(map[Point]map[Point]float64)
+	for _, k := range pairs {
+		d := m[k]
+		if d <= 0 || k.A == k.B {
+			continue
+		}
+		r.Demands[k.B] += d
+		if sources[k.B] == nil {
+			sources[k.B] = make(map[Point]float64)
+		}
+		sources[k.B][k.A] += d
+
+		if routes[k.A] == nil {
+			routes[k.A] = policy.Routes(g, k.A)
+		}
+		path, ok := routes[k.A][k.B]
+		if !ok {
+			r.Unrouted = append(r.Unrouted, k)
+			continue
+		}
+		for i, p := range path {
+			load(p).Load += d
+			if i > 0 {
+				links[Pair{path[i-1], p}] += d
+				if i < len(path)-1 {
+					load(p).Relayed += d
+				}
+			}
+		}
+	}
+
+	for k, v := range links {
+		p, _ := g.Edge(k.A, k.B)
+		r.Links = append(r.Links, LinkLoad{k, v, float64(p.Bandwidth)})
+	}
+	sort.SliceStable(r.Links, func(i, j int) bool {
+		x, y := r.Links[i], r.Links[j]
+		if x.Utilization() != y.Utilization() {
+			return x.Utilization() > y.Utilization()
+		}
+		return x.Load > y.Load
+	})
+	for _, l := range loads {
+		r.IDCs = append(r.IDCs, *l)
+	}
+	sort.SliceStable(r.IDCs, func(i, j int) bool {
+		x, y := r.IDCs[i], r.IDCs[j]
+		if x.Utilization() != y.Utilization() {
+			return x.Utilization() > y.Utilization()
+		}
+		return x.Load > y.Load
+	})
+
+	for sink, s := range sources {
+		r.MaxFlows[sink] = MaxFlow(g, idcs, s, sink)
+	}
+	return r
+}
+
+// MaxFlow returns the maximum flow in kbit/s from sources to the sink by
+// Edmonds-Karp algorithm, where each source sends no more than its supply,
+// links are limited by bandwidths and points are limited by capacities of
+// given map. Each point is split into the in and out halves connected by its
+// capacity, so all traffic sent, received or relayed by a point is limited.
+func MaxFlow(g *Graph, idcs map[Point]float64, supplies map[Point]float64, sink Point) float64 {
+	points := g.Points()
+	index := make(map[Point]int, len(points))
+	for i, p := range points {
+		index[p] = i
+	}
+	if _, ok := index[sink]; !ok {
+		return 0
+	}
+
+	// nodes are 2i for in halves, 2i+1 for out halves, and the super source
+	n := 2*len(points) + 1
+	source := n - 1
+	capacity := make([]map[int]float64, n)
+	for i := range capacity {
+		capacity[i] = make(map[int]float64)
+	}
+	limit := func(v float64) float64 {
+		if v <= 0 {
+			return math.Inf(1)
+		}
+		return v
+	}
+	for i, a := range points {
+		capacity[2*i][2*i+1] = limit(idcs[a])
+		for b, p := range g.edges[a] {
+			capacity[2*i+1][2*index[b]] += limit(float64(p.Bandwidth))
+		}
+	}
+	for p, v := range supplies {
+		if i, ok := index[p]; ok && p != sink {
+			capacity[source][2*i] += v
+		}
+	}
+	target := 2*index[sink] + 1
+
+	var flow float64
+	for {
+		prev := make([]int, n)
+		for i := range prev {
+			prev[i] = -1
+		}
+		prev[source] = source
+		queue := []int{source}
+		for len(queue) > 0 && prev[target] < 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for v, c := range capacity[u] {
+				if prev[v] < 0 && c > 1e-9 {
+					prev[v] = u
+					queue = append(queue, v)
+				}
+			}
+		}
+		if prev[target] < 0 {
+			return flow
+		}
+
+		f := math.Inf(1)
+		for v := target; v != source; v = prev[v] {
+			f = math.Min(f, capacity[prev[v]][v])
+		}
+		if math.IsInf(f, 1) {
+			return f
+		}
+		for v := target; v != source; v = prev[v] {
+			capacity[prev[v]][v] -= f
+			capacity[v][prev[v]] += f
+		}
+		flow += f
+	}
+}
@@ -0,0 +1,105 @@
+package simnet
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPlanCapacity(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["南京市"], ISP: 1}
+	x := Point{City: cities["上海市"], ISP: 1}
+	y := Point{City: cities["武汉市"], ISP: 1}
+	c := Point{City: cities["北京市"], ISP: 1}
+	link := func(a, b Point, latency time.Duration) Link {
+		return Link{A: a, B: b, Profile: Profile{Latency: latency, Bandwidth: 1000}}
+	}
+	g := NewGraph(Affinity{
+		link(a, x, 10*time.Millisecond),
+		link(b, x, 10*time.Millisecond),
+		link(x, c, 10*time.Millisecond),
+		link(b, y, 20*time.Millisecond),
+		link(y, c, 20*time.Millisecond),
+	})
+	idcs := map[Point]float64{x: 1500}
+	m := TrafficMatrix{{a, c}: 800, {b, c}: 800}
+
+	r := PlanCapacity(g, idcs, ShortestPath{}, m)
+	if l := r.Bottlenecks(); len(l) != 1 || l[0].Pair != (Pair{x, c}) || l[0].Load != 1600 {
+		t.Errorf("expected link from %v to %v loaded 1600, got %v", x, c, l)
+	}
+	if l := r.Overloaded(); len(l) != 1 || l[0].Point != x || l[0].Relayed != 1600 {
+		t.Errorf("expected %v overloaded by relaying 1600, got %v", x, l)
+	}
+	if !r.Feasible() || r.MaxFlows[c] != 1600 {
+		t.Errorf("expected max flow 1600 through both relays, got %v", r.MaxFlows)
+	}
+
+	idcs[c] = 1000
+	if r := PlanCapacity(g, idcs, ShortestPath{}, m); r.Feasible() || r.MaxFlows[c] != 1000 {
+		t.Errorf("expected max flow limited to 1000 by %v, got %v", c, r.MaxFlows)
+	}
+	if f := MaxFlow(g, nil, map[Point]float64{a: math.Inf(1)}, c); f != 1000 {
+		t.Errorf("expected max flow 1000 from %v, got %v", a, f)
+	}
+	if r := PlanCapacity(g, nil, ShortestPath{}, TrafficMatrix{{c, a}: 1}); len(r.Unrouted) != 1 {
+		t.Errorf("expected unrouted demand, got %v", r.Unrouted)
+	}
+
+	t.Run("Capitals to cores", func(t *testing.T) {
+		var points, cores []Point
+		for _, name := range names {
+			if Tier(name) <= 3 {
+				points = append(points, Point{City: cities[name], ISP: isps[0]})
+			}
+			if Tier(name) == 1 {
+				cores = append(cores, points[len(points)-1])
+			}
+		}
+		m := make(TrafficMatrix)
+		for _, p := range points {
+			for _, c := range cores {
+				if p != c {
+					m[Pair{p, c}] = 100
+				}
+			}
+		}
+
+		// latencies are by geographical distances plus an overhead of each
+		// hop, so that routes are not chosen by ties of the model
+		graph := NewAffinity(points)
+		for i, z := range graph {
+			if z.PacketLoss < 100 {
+				x, _ := GetLocation(z.A.City.Name)
+				y, _ := GetLocation(z.B.City.Name)
+				d := math.Hypot(x.Longitude-y.Longitude, x.Latitude-y.Latitude)
+				graph[i].Profile = Profile{Latency: time.Duration((1 + d) * float64(time.Millisecond))}
+			}
+		}
+
+		// each core is capable of only the traffic to and from itself, so
+		// it's overloaded if relaying anything
+		idcs := make(map[Point]float64)
+		for k, v := range m {
+			for _, c := range cores {
+				if k.A == c || k.B == c {
+					idcs[c] += v
+				}
+			}
+		}
+
+		r := PlanCapacity(NewGraph(graph), idcs, TierTree{}, m)
+		if len(r.Unrouted) > 0 {
+			t.Errorf("expected all routed, got %v unrouted", len(r.Unrouted))
+		}
+		if o := r.Overloaded(); len(o) > 0 {
+			t.Errorf("expected no core overloaded, got %+v", o)
+		}
+		for _, l := range r.IDCs {
+			if Tier(l.Point.City.Name) == 1 && l.Relayed > 0 {
+				t.Errorf("expected %v relaying nothing, got %v kbit/s", l.Point, l.Relayed)
+			}
+		}
+	})
+}
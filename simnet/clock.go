@@ -0,0 +1,128 @@
+package simnet
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells and waits time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses for at least the duration d.
+	Sleep(d time.Duration)
+	// After waits for the duration d then sends the current time on the channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the wall clock.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// NewScaledClock returns a clock running scale times faster than the wall
+// clock, starting from the current time.
+func NewScaledClock(scale float64) Clock {
+	now := time.Now()
+	return &scaledClock{since: now, start: now, scale: scale}
+}
+
+type scaledClock struct {
+	since time.Time
+	start time.Time
+	scale float64
+}
+
+func (c *scaledClock) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.since)) * c.scale))
+}
+
+func (c *scaledClock) Sleep(d time.Duration) {
+	time.Sleep(time.Duration(float64(d) / c.scale))
+}
+
+func (c *scaledClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	time.AfterFunc(time.Duration(float64(d)/c.scale), func() {
+		ch <- c.Now()
+	})
+	return ch
+}
+
+// SimClock is a simulated clock, whose time only moves on Advance.
+type SimClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []simWaiter
+}
+
+type simWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewSimClock returns a simulated clock starting from given time.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+// Now implements the Clock interface.
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Sleep implements the Clock interface.
+func (c *SimClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// After implements the Clock interface.
+func (c *SimClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, simWaiter{c.now.Add(d), ch})
+	return ch
+}
+
+// Waiters returns the number of pending waiters.
+func (c *SimClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// Advance moves the time forward by d, and wakes waiters in order of time.
+func (c *SimClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].at.Before(c.waiters[j].at)
+	})
+
+	n := 0
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			break
+		}
+		w.ch <- w.at
+		n++
+	}
+	c.waiters = c.waiters[n:]
+}
@@ -0,0 +1,47 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimClock(t *testing.T) {
+	start := time.Date(2017, 10, 23, 0, 0, 0, 0, time.UTC)
+	c := NewSimClock(start)
+
+	a, b := c.After(2*time.Second), c.After(time.Second)
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-a:
+		t.Fatal("expected not fired")
+	case <-b:
+		t.Fatal("expected not fired")
+	default:
+	}
+
+	c.Advance(2 * time.Second)
+	if v := <-b; !v.Equal(start.Add(time.Second)) {
+		t.Errorf("expected %v, got %v", start.Add(time.Second), v)
+	}
+	if v := <-a; !v.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("expected %v, got %v", start.Add(2*time.Second), v)
+	}
+	if now := c.Now(); !now.Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("expected %v, got %v", start.Add(2500*time.Millisecond), now)
+	}
+	if n := c.Waiters(); n != 0 {
+		t.Errorf("expected no waiters, got %v", n)
+	}
+}
+
+func TestScaledClock(t *testing.T) {
+	c := NewScaledClock(100)
+	since, now := time.Now(), c.Now()
+	c.Sleep(10 * time.Second)
+	if elapsed := time.Since(since); elapsed > time.Second {
+		t.Errorf("expected elapse about 100ms, got %v", elapsed)
+	}
+	if elapsed := c.Now().Sub(now); elapsed < 10*time.Second {
+		t.Errorf("expected simulated elapse >= 10s, got %v", elapsed)
+	}
+}
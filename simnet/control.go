@@ -0,0 +1,144 @@
+package simnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Server is a server of network.
+type Server struct {
+	Port  int
+	Point Point
+}
+
+// ControlHandler returns an HTTP handler to inspect and change the network
+// at runtime, all bodies are encoded in JSON.
+//
+// Supported requests:
+//
+//	GET  /servers                      - lists all servers
+//	GET  /links?a=PORT&b=PORT          - reads the profile of link between servers
+//	PUT  /links?a=PORT&b=PORT          - updates the profile of link between servers
+//	POST /cut?SELECTOR                 - cuts selected points off
+//	POST /restore?SELECTOR             - restores selected points
+//	GET  /affinity                     - dumps the current affinity
+//
+// The SELECTOR is a query of city, province, district and isp.
+func (n *Network) ControlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/servers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var servers []Server
+		for _, port := range n.Ports() {
+			p, _ := n.Point(port)
+			servers = append(servers, Server{port, p})
+		}
+		writeJSON(w, servers)
+	})
+	mux.HandleFunc("/links", func(w http.ResponseWriter, r *http.Request) {
+		a, err := n.queryPoint(r, "a")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := n.queryPoint(r, "b")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			writeJSON(w, n.Profile(a, b))
+		case "PUT":
+			var p Profile
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			n.SetProfile(a, b, p)
+			writeJSON(w, n.Profile(a, b))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/cut", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s, err := querySelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.Cut(s)
+	})
+	mux.HandleFunc("/restore", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s, err := querySelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.Restore(s)
+	})
+	mux.HandleFunc("/affinity", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, n.Affinity())
+	})
+	return mux
+}
+
+func (n *Network) queryPoint(r *http.Request, key string) (Point, error) {
+	port, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid %v: %v", key, err)
+	}
+
+	p, ok := n.Point(port)
+	if !ok {
+		return Point{}, fmt.Errorf("unknown server: %v", port)
+	}
+	return p, nil
+}
+
+func querySelector(r *http.Request) (Selector, error) {
+	q := r.URL.Query()
+	s := Selector{
+		City:     q.Get("city"),
+		Province: q.Get("province"),
+		District: q.Get("district"),
+	}
+	if v := q.Get("isp"); v != "" {
+		isp, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return s, fmt.Errorf("invalid isp: %v", err)
+		}
+		s.ISP = ISP(isp)
+	}
+	if s == (Selector{}) {
+		return s, fmt.Errorf("empty selector")
+	}
+	return s, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
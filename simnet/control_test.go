@@ -0,0 +1,103 @@
+package simnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlHandler(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	ts := httptest.NewServer(network.ControlHandler())
+	defer ts.Close()
+
+	var servers []Server
+	res, err := http.Get(ts.URL + "/servers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(res.Body).Decode(&servers)
+	res.Body.Close()
+	if len(servers) != 5 {
+		t.Fatalf("expected 5 servers, got %v", len(servers))
+	}
+
+	a, b := servers[0], servers[1]
+	links := fmt.Sprintf("%s/links?a=%d&b=%d", ts.URL, a.Port, b.Port)
+
+	t.Run("Update link", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", links, strings.NewReader(`{"PacketLoss":10,"Latency":5000000}`))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != 200 {
+			t.Fatalf("expected 200, got %v", res.StatusCode)
+		}
+
+		var p Profile
+		res, err = http.Get(links)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(res.Body).Decode(&p)
+		res.Body.Close()
+		if p != (Profile{PacketLoss: 10, Latency: 5 * time.Millisecond}) {
+			t.Errorf("unexpected profile %+v", p)
+		}
+	})
+
+	t.Run("Cut and restore", func(t *testing.T) {
+		q := url.Values{"province": {a.Point.City.Province}}.Encode()
+		res, err := http.Post(ts.URL+"/cut?"+q, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		var affinity Affinity
+		res, err = http.Get(ts.URL + "/affinity")
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(res.Body).Decode(&affinity)
+		res.Body.Close()
+		for _, z := range affinity {
+			if z.A.City.Province == a.Point.City.Province && z.B.City.Province != a.Point.City.Province && z.PacketLoss != 100 {
+				t.Errorf("expected unreachable from %v to %v, got %v%% packet loss", z.A.City, z.B.City, z.PacketLoss)
+			}
+		}
+
+		res, err = http.Post(ts.URL+"/restore?"+q, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if len(network.faults) != 0 {
+			t.Errorf("expected no faults, got %v", network.faults)
+		}
+	})
+
+	t.Run("Empty selector", func(t *testing.T) {
+		res, err := http.Post(ts.URL+"/cut", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400, got %v", res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package simnet
+
+import (
+	"sort"
+	"time"
+)
+
+// Class is a traffic class, e.g. "bulk" or "interactive".
+type Class string
+
+// Pricing contains bandwidth prices per GB of traffic.
+type Pricing struct {
+	// ISPs are prices of links from points of each ISP.
+	ISPs map[ISP]float64
+	// CrossISP is the extra price of links across ISPs.
+	CrossISP float64
+	// IDCs are extra prices of relaying by points, e.g. multi-rooms.
+	IDCs map[Point]float64
+	// Classes are multipliers of traffic classes, 1 if missing.
+	Classes map[Class]float64
+}
+
+// Cost returns the price per GB of traffic of the class along the path,
+// where each link is charged by the ISP of its start, and each intermediate
+// point is charged by the IDC.
+func (p *Pricing) Cost(path Path, class Class) float64 {
+	var cost float64
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		cost += p.ISPs[a.ISP]
+		if a.ISP != b.ISP {
+			cost += p.CrossISP
+		}
+		if i > 1 {
+			cost += p.IDCs[a]
+		}
+	}
+	if m, ok := p.Classes[class]; ok {
+		cost *= m
+	}
+	return cost
+}
+
+// SLO is the service level objective of routes, a zero field is unbounded.
+type SLO struct {
+	Latency    time.Duration
+	PacketLoss int
+}
+
+// Meet reports whether the profile meets the objective.
+func (s SLO) Meet(p Profile) bool {
+	return (s.Latency == 0 || p.Latency <= s.Latency) &&
+		(s.PacketLoss == 0 || p.PacketLoss <= s.PacketLoss)
+}
+
+// CostAware is the policy choosing the cheapest paths meeting the SLO among
+// the K shortest paths, a destination is unreachable if no such path.
+type CostAware struct {
+	Pricing *Pricing
+	Class   Class
+	SLO     SLO
+	// K is the number of candidates of each destination, 1 if non-positive.
+	K int
+}
+
+// Routes implements Policy.
+func (c CostAware) Routes(g *Graph, from Point) map[Point]Path {
+	paths := map[Point]Path{from: {from}}
+	for b, r := range c.cheapest(g, from) {
+		paths[b] = r.Path
+	}
+	return paths
+}
+
+func (c CostAware) cheapest(g *Graph, from Point) map[Point]PricedRoute {
+	k := c.K
+	if k < 1 {
+		k = 1
+	}
+
+	routes := make(map[Point]PricedRoute)
+	for b := range g.ShortestPaths(from) {
+		for _, r := range g.KShortestPaths(from, b, k) {
+			if !c.SLO.Meet(r.Profile) {
+				continue
+			}
+			cost := c.Pricing.Cost(r.Path, c.Class)
+			if old, ok := routes[b]; !ok || cost < old.Cost {
+				routes[b] = PricedRoute{r, cost}
+			}
+		}
+	}
+	return routes
+}
+
+// PricedRoute is a route with the estimated cost per GB.
+type PricedRoute struct {
+	Route
+	Cost float64
+}
+
+// Estimate returns the cheapest routes meeting the SLO of all ordered pairs
+// with costs, in order of the start and end points.
+func (c CostAware) Estimate(g *Graph) []PricedRoute {
+	var routes []PricedRoute
+	for _, a := range g.Points() {
+		for _, r := range c.cheapest(g, a) {
+			routes = append(routes, r)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		x, y := routes[i].Path, routes[j].Path
+		return lessPair(Pair{x[0], x[len(x)-1]}, Pair{y[0], y[len(y)-1]})
+	})
+	return routes
+}
@@ -0,0 +1,52 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCostAware(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["昆明市"], ISP: 1}
+	x := Point{City: cities["上海市"], ISP: 2}
+	y := Point{City: cities["北京市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: x, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: x, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: a, B: y, Profile: Profile{Latency: 30 * time.Millisecond, PacketLoss: 10}},
+		{A: y, B: b, Profile: Profile{Latency: 30 * time.Millisecond}},
+	})
+	pricing := &Pricing{
+		ISPs:     map[ISP]float64{1: 1, 2: 2},
+		CrossISP: 3,
+		IDCs:     map[Point]float64{y: 1},
+		Classes:  map[Class]float64{"bulk": 0.5},
+	}
+
+	if c := pricing.Cost(Path{a, x, b}, ""); c != 1+3+2+3 {
+		t.Errorf("expected cost 9 through %v, got %v", x, c)
+	}
+	if c := pricing.Cost(Path{a, y, b}, "bulk"); c != (1+1+1)*0.5 {
+		t.Errorf("expected cost 1.5 through %v, got %v", y, c)
+	}
+
+	if path := (CostAware{Pricing: pricing, K: 3}).Routes(g, a)[b]; len(path) != 3 || path[1] != y {
+		t.Errorf("expected the cheapest path through %v, got %v", y, path)
+	}
+	if path := (CostAware{Pricing: pricing, K: 3, SLO: SLO{Latency: 50 * time.Millisecond}}).Routes(g, a)[b]; len(path) != 3 || path[1] != x {
+		t.Errorf("expected the path through %v meeting latency, got %v", x, path)
+	}
+	if _, ok := (CostAware{Pricing: pricing, K: 3, SLO: SLO{Latency: 50 * time.Millisecond, PacketLoss: 5}}).Routes(g, a)[y]; ok {
+		t.Errorf("expected %v unreachable by loss", y)
+	}
+
+	routes := CostAware{Pricing: pricing, Class: "bulk", K: 3}.Estimate(g)
+	if len(routes) != 5 {
+		t.Fatalf("expected 5 routes, got %v", routes)
+	}
+	for _, r := range routes {
+		if r.Path[0] == a && r.Path[len(r.Path)-1] == b && r.Cost != 1.5 {
+			t.Errorf("expected estimated cost 1.5, got %v", r.Cost)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package simnet
+
+import (
+	"math"
+	"time"
+)
+
+// Drift is the difference of a link between a modelled and a measured
+// affinity.
+type Drift struct {
+	A        Point
+	B        Point
+	Model    Profile
+	Measured Profile
+}
+
+// PacketLoss returns the measured minus modelled packet loss.
+func (d Drift) PacketLoss() int {
+	return d.Measured.PacketLoss - d.Model.PacketLoss
+}
+
+// Latency returns the measured minus modelled latency, which is zero if the
+// link is unreachable in either affinity.
+func (d Drift) Latency() time.Duration {
+	if d.Model.PacketLoss == 100 || d.Measured.PacketLoss == 100 {
+		return 0
+	}
+	return d.Measured.Latency - d.Model.Latency
+}
+
+// Misclassified reports whether the link is reachable in only one affinity.
+func (d Drift) Misclassified() bool {
+	return (d.Model.PacketLoss == 100) != (d.Measured.PacketLoss == 100)
+}
+
+// DriftSummary aggregates drifts of a group of links.
+type DriftSummary struct {
+	// Links is the number of links.
+	Links int
+	// Misclassified is the number of links reachable in only one affinity.
+	Misclassified int
+	// PacketLoss is the mean absolute drift of packet loss.
+	PacketLoss float64
+	// Latency is the mean absolute drift of latency of links reachable in
+	// both affinities.
+	Latency time.Duration
+
+	reachable int
+}
+
+func (s *DriftSummary) add(d Drift) {
+	s.Links++
+	if d.Misclassified() {
+		s.Misclassified++
+	}
+	s.PacketLoss += (math.Abs(float64(d.PacketLoss())) - s.PacketLoss) / float64(s.Links)
+	if d.Model.PacketLoss < 100 && d.Measured.PacketLoss < 100 {
+		s.reachable++
+		latency := d.Latency()
+		if latency < 0 {
+			latency = -latency
+		}
+		s.Latency += (latency - s.Latency) / time.Duration(s.reachable)
+	}
+}
+
+// DriftReport contains drifts of all links existing in both affinities, and
+// the summaries grouped by attributes of both ends.
+type DriftReport struct {
+	Drifts     []Drift
+	Total      DriftSummary
+	ByProvince map[[2]string]*DriftSummary
+	ByDistrict map[[2]string]*DriftSummary
+	ByISP      map[[2]ISP]*DriftSummary
+	ByTier     map[[2]int]*DriftSummary
+}
+
+// NewDriftReport compares a measured affinity to the modelled one.
+func NewDriftReport(model, measured Affinity) *DriftReport {
+	profiles := make(map[Pair]Profile, len(measured))
+	for _, z := range measured {
+		profiles[Pair{z.A, z.B}] = z.Profile
+	}
+
+	r := &DriftReport{
+		ByProvince: make(map[[2]string]*DriftSummary),
+		ByDistrict: make(map[[2]string]*DriftSummary),
+		ByISP:      make(map[[2]ISP]*DriftSummary),
+		ByTier:     make(map[[2]int]*DriftSummary),
+	}
+	for _, z := range model {
+		p, ok := profiles[Pair{z.A, z.B}]
+		if !ok {
+			continue
+		}
+
+		d := Drift{A: z.A, B: z.B, Model: z.Profile, Measured: p}
+		r.Drifts = append(r.Drifts, d)
+		r.Total.add(d)
+
+		province := [2]string{z.A.City.Province, z.B.City.Province}
+		if r.ByProvince[province] == nil {
+			r.ByProvince[province] = new(DriftSummary)
+		}
+		r.ByProvince[province].add(d)
+
+		district := [2]string{z.A.City.District, z.B.City.District}
+		if r.ByDistrict[district] == nil {
+			r.ByDistrict[district] = new(DriftSummary)
+		}
+		r.ByDistrict[district].add(d)
+
+		isp := [2]ISP{z.A.ISP, z.B.ISP}
+		if r.ByISP[isp] == nil {
+			r.ByISP[isp] = new(DriftSummary)
+		}
+		r.ByISP[isp].add(d)
+
+		tier := [2]int{Tier(z.A.City.Name), Tier(z.B.City.Name)}
+		if r.ByTier[tier] == nil {
+			r.ByTier[tier] = new(DriftSummary)
+		}
+		r.ByTier[tier].add(d)
+	}
+	return r
+}
+
+// Misclassified returns drifts of links reachable in only one affinity.
+func (r *DriftReport) Misclassified() []Drift {
+	var drifts []Drift
+	for _, d := range r.Drifts {
+		if d.Misclassified() {
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts
+}
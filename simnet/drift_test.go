@@ -0,0 +1,63 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDriftReport(t *testing.T) {
+	a := Point{City: cities["北京市"], ISP: 1}
+	b := Point{City: cities["石家庄市"], ISP: 1}
+	c := Point{City: cities["广州市"], ISP: 2}
+	model := Affinity{
+		{A: a, B: b, Profile: Profile{PacketLoss: 10, Latency: 20 * time.Millisecond}},
+		{A: b, B: a, Profile: Profile{PacketLoss: 10, Latency: 20 * time.Millisecond}},
+		{A: a, B: c, Profile: Profile{PacketLoss: 100}},
+		{A: c, B: a, Profile: Profile{PacketLoss: 30, Latency: 50 * time.Millisecond}},
+	}
+	measured := Affinity{
+		{A: a, B: b, Profile: Profile{PacketLoss: 20, Latency: 10 * time.Millisecond}},
+		{A: b, B: a, Profile: Profile{PacketLoss: 10, Latency: 40 * time.Millisecond}},
+		{A: a, B: c, Profile: Profile{PacketLoss: 0, Latency: 60 * time.Millisecond}},
+	}
+
+	r := NewDriftReport(model, measured)
+	if len(r.Drifts) != 3 {
+		t.Fatalf("expected 3 drifts, got %v", len(r.Drifts))
+	}
+	if d := r.Drifts[0]; d.PacketLoss() != 10 || d.Latency() != -10*time.Millisecond {
+		t.Errorf("unexpected drift %+v", d)
+	}
+	if m := r.Misclassified(); len(m) != 1 || m[0].A != a || m[0].B != c {
+		t.Errorf("expected misclassified %v to %v, got %+v", a, c, m)
+	}
+
+	if s := r.Total; s.Links != 3 || s.Misclassified != 1 || s.Latency != 15*time.Millisecond {
+		t.Errorf("unexpected total %+v", s)
+	}
+	if s := r.ByProvince[[2]string{"北京市", "河北省"}]; s == nil || s.Links != 1 || s.PacketLoss != 10 {
+		t.Errorf("unexpected summary of 北京市 to 河北省 %+v", s)
+	}
+	if s := r.ByDistrict[[2]string{"华北", "华北"}]; s == nil || s.Links != 2 || s.PacketLoss != 5 {
+		t.Errorf("unexpected summary within 华北 %+v", s)
+	}
+	if s := r.ByISP[[2]ISP{1, 2}]; s == nil || s.Misclassified != 1 {
+		t.Errorf("unexpected summary of ISP 1 to 2 %+v", s)
+	}
+	if s := r.ByTier[[2]int{1, 3}]; s == nil || s.Links != 1 {
+		t.Errorf("unexpected summary of tier 1 to 3 %+v", s)
+	}
+}
+
+func TestTier(t *testing.T) {
+	for city, tier := range map[string]int{
+		"北京市":     1,
+		"成都市":     2,
+		"昆明市":     3,
+		"大理白族自治州": 4,
+	} {
+		if v := Tier(city); v != tier {
+			t.Errorf("expected tier %v of %v, got %v", tier, city, v)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package simnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Distance is the cost to a destination and the next hop port.
+type Distance struct {
+	Cost    time.Duration
+	NextHop int
+}
+
+// Vector maps destination ports to distances.
+type Vector map[int]Distance
+
+// VectorChange is a change of the distance from a server to a destination,
+// an unreachable destination is of zero distance.
+type VectorChange struct {
+	Time        time.Time
+	Port        int
+	Destination int
+	Distance    Distance
+}
+
+// DistanceVector runs a distance-vector routing protocol among all servers of
+// network, where each server periodically fetches the vector of every other
+// server by GET /vector, the round-trip time of which smoothed like SRTT of
+// TCP is the cost of link, then updates its vector by Bellman-Ford equations.
+type DistanceVector struct {
+	Network *Network
+	// Period is the interval of exchanges, 1s if non-positive.
+	Period time.Duration
+	// Timeout is the timeout of each exchange, Period if non-positive. A
+	// neighbor failing to exchange is unreachable until next exchange.
+	Timeout time.Duration
+	// Infinity is the cost regarded as unreachable, which stops counting to
+	// infinity, unreachablePenalty if non-positive.
+	Infinity time.Duration
+	// Tolerance is the maximum change of cost which is not recorded as a
+	// change, so that jitters of round-trip times are ignored.
+	Tolerance time.Duration
+	// PoisonReverse makes a server advertise routes through a neighbor back
+	// to the neighbor as unreachable.
+	PoisonReverse bool
+
+	mu      sync.Mutex
+	vectors map[int]Vector
+	changes []VectorChange
+}
+
+func (dv *DistanceVector) period() time.Duration {
+	if dv.Period <= 0 {
+		return time.Second
+	}
+	return dv.Period
+}
+
+func (dv *DistanceVector) timeout() time.Duration {
+	if dv.Timeout <= 0 {
+		return dv.period()
+	}
+	return dv.Timeout
+}
+
+func (dv *DistanceVector) infinity() time.Duration {
+	if dv.Infinity <= 0 {
+		return unreachablePenalty
+	}
+	return dv.Infinity
+}
+
+// Run runs the protocol on all servers until ctx is done.
+func (dv *DistanceVector) Run(ctx context.Context) error {
+	ports := dv.Network.Ports()
+	dv.mu.Lock()
+	dv.vectors = make(map[int]Vector, len(ports))
+	for _, port := range ports {
+		dv.vectors[port] = Vector{port: {0, port}}
+	}
+	dv.mu.Unlock()
+
+	dv.Network.Handle("/vector", dv.serve)
+	defer dv.Network.Handle("/vector", nil)
+
+	var wg sync.WaitGroup
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			dv.run(ctx, port, ports)
+		}(port)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// serve returns the vector of server to the neighbor.
+func (dv *DistanceVector) serve(port int, w http.ResponseWriter, r *http.Request) {
+	from, _ := strconv.Atoi(r.Header.Get(FromHeader))
+	v := dv.Vector(port)
+	if dv.PoisonReverse {
+		for dest, d := range v {
+			if d.NextHop == from && dest != port {
+				v[dest] = Distance{dv.infinity(), from}
+			}
+		}
+	}
+	writeJSON(w, v)
+}
+
+func (dv *DistanceVector) run(ctx context.Context, port int, ports []int) {
+	clock := dv.Network.Clock()
+	costs := make(map[int]time.Duration)
+	for {
+		select {
+		case <-clock.After(dv.period()):
+		case <-ctx.Done():
+			return
+		}
+
+		var (
+			mu        sync.Mutex
+			wg        sync.WaitGroup
+			neighbors = make(map[int]Vector)
+		)
+		for _, to := range ports {
+			if to == port {
+				continue
+			}
+			wg.Add(1)
+			go func(to int) {
+				defer wg.Done()
+				since := clock.Now()
+				v, err := dv.fetch(ctx, port, to)
+				rtt := clock.Now().Sub(since)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					delete(costs, to)
+				} else if cost, ok := costs[to]; ok {
+					costs[to], neighbors[to] = cost+(rtt-cost)/8, v
+				} else {
+					costs[to], neighbors[to] = rtt, v
+				}
+			}(to)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		next := Vector{port: {0, port}}
+		for to, v := range neighbors {
+			for dest, d := range v {
+				cost := costs[to] + d.Cost
+				if old, ok := next[dest]; cost < dv.infinity() && (!ok || cost < old.Cost) {
+					next[dest] = Distance{cost, to}
+				}
+			}
+		}
+		dv.update(port, next, clock.Now())
+	}
+}
+
+func (dv *DistanceVector) fetch(ctx context.Context, from, to int) (Vector, error) {
+	ctx, cancel := context.WithTimeout(ctx, dv.timeout())
+	defer cancel()
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/vector", to), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(FromHeader, fmt.Sprint(from))
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	var v Vector
+	err = json.NewDecoder(res.Body).Decode(&v)
+	return v, err
+}
+
+// update replaces the vector of server, and records significant changes.
+func (dv *DistanceVector) update(port int, v Vector, now time.Time) {
+	dv.mu.Lock()
+	defer dv.mu.Unlock()
+
+	old := dv.vectors[port]
+	var dests []int
+	for dest := range old {
+		dests = append(dests, dest)
+	}
+	for dest := range v {
+		if _, ok := old[dest]; !ok {
+			dests = append(dests, dest)
+		}
+	}
+	sort.Ints(dests)
+
+	for _, dest := range dests {
+		x, okX := old[dest]
+		y, okY := v[dest]
+		delta := y.Cost - x.Cost
+		if okX != okY || x.NextHop != y.NextHop || delta > dv.Tolerance || -delta > dv.Tolerance {
+			dv.changes = append(dv.changes, VectorChange{now, port, dest, y})
+		}
+	}
+	dv.vectors[port] = v
+}
+
+// Vector returns a copy of the current vector of server on given port.
+func (dv *DistanceVector) Vector(port int) Vector {
+	dv.mu.Lock()
+	defer dv.mu.Unlock()
+
+	v := make(Vector, len(dv.vectors[port]))
+	for dest, d := range dv.vectors[port] {
+		v[dest] = d
+	}
+	return v
+}
+
+// Changes returns recorded changes since given time in order, which shows
+// convergence and counting to infinity.
+func (dv *DistanceVector) Changes(since time.Time) []VectorChange {
+	dv.mu.Lock()
+	defer dv.mu.Unlock()
+
+	var changes []VectorChange
+	for _, c := range dv.changes {
+		if !c.Time.Before(since) {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Converged returns the time of the last change, and whether no change
+// happens within the last given duration, e.g. a few periods.
+func (dv *DistanceVector) Converged(quiet time.Duration) (time.Time, bool) {
+	dv.mu.Lock()
+	defer dv.mu.Unlock()
+
+	var last time.Time
+	if len(dv.changes) > 0 {
+		last = dv.changes[len(dv.changes)-1].Time
+	}
+	return last, dv.Network.Clock().Now().Sub(last) >= quiet
+}
+
+// Install loads the forwarding tables of all servers by current vectors.
+func (dv *DistanceVector) Install() {
+	for _, port := range dv.Network.Ports() {
+		dv.Network.LoadTable(port, dv.Network.vectorTable(port, dv.Vector(port)))
+	}
+}
+
+// vectorTable converts a vector of server on given port to the forwarding
+// table, where the destination of least cost is chosen among servers of the
+// same point.
+func (n *Network) vectorTable(port int, v Vector) ForwardingTable {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	t := ForwardingTable{Point: n.servers[port]}
+	costs := make(map[Point]time.Duration)
+	index := make(map[Point]int)
+	for dest, d := range v {
+		p, next := n.servers[dest], n.servers[d.NextHop]
+		if p == t.Point {
+			continue
+		}
+		if cost, ok := costs[p]; ok && cost <= d.Cost {
+			continue
+		}
+		costs[p] = d.Cost
+		if i, ok := index[p]; ok {
+			t.Entries[i].NextHop = next
+		} else {
+			index[p] = len(t.Entries)
+			t.Entries = append(t.Entries, Forward{Destination: p, NextHop: next})
+		}
+	}
+	sort.Slice(t.Entries, func(i, j int) bool {
+		return lessPoint(t.Entries[i].Destination, t.Entries[j].Destination)
+	})
+	return t
+}
@@ -0,0 +1,136 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// chainNetwork creates a network where the first 4 points are linked in a
+// chain of 10ms in both directions, and other links are unreachable.
+func chainNetwork(t *testing.T) (*Network, []Point, []int) {
+	network, err := NewNetwork(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points := network.Points()
+	if len(points) < 4 {
+		network.Close()
+		t.Skip("expected at least 4 distinct points, got", len(points))
+	}
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				network.SetProfile(a, b, Profile{PacketLoss: 100})
+			}
+		}
+	}
+	points = points[:4]
+	var ports []int
+	for i, p := range points {
+		port, _ := network.port(p)
+		ports = append(ports, port)
+		if i > 0 {
+			network.SetProfile(points[i-1], p, Profile{Latency: 10 * time.Millisecond})
+			network.SetProfile(p, points[i-1], Profile{Latency: 10 * time.Millisecond})
+		}
+	}
+	return network, points, ports
+}
+
+// converge waits until done reports the expected routes or the timeout, it
+// returns the time waited.
+func converge(t *testing.T, done func() bool, timeout time.Duration) time.Duration {
+	since := time.Now()
+	for time.Since(since) < timeout {
+		if done() {
+			return time.Since(since)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected converged in %v", timeout)
+	return 0
+}
+
+func TestDistanceVector(t *testing.T) {
+	counts := make(map[bool]int)
+	for _, poison := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Poison reverse %v", poison), func(t *testing.T) {
+			counts[poison] = testDistanceVector(t, poison)
+		})
+	}
+	if !t.Failed() && counts[true] >= counts[false] {
+		t.Errorf("expected counting to infinity without poison reverse, got %v", counts)
+	}
+}
+
+// testDistanceVector runs the protocol on a chain, then cuts the end off, it
+// returns the number of changes of the route from the head to the end.
+func testDistanceVector(t *testing.T, poison bool) int {
+	network, points, ports := chainNetwork(t)
+	defer network.Close()
+
+	// the timeout is long enough that a slow exchange doesn't lose a route
+	dv := &DistanceVector{
+		Network:       network,
+		Period:        30 * time.Millisecond,
+		Timeout:       time.Second,
+		Infinity:      time.Second,
+		Tolerance:     10 * time.Millisecond,
+		PoisonReverse: poison,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dv.Run(ctx)
+
+	elapsed := converge(t, func() bool {
+		for _, port := range ports {
+			v := dv.Vector(port)
+			for _, dest := range ports {
+				if _, ok := v[dest]; !ok {
+					return false
+				}
+			}
+		}
+		return true
+	}, 30*time.Second)
+	t.Logf("converged in %v", elapsed)
+
+	d, ok := dv.Vector(ports[0])[ports[3]]
+	if p, _ := network.Point(d.NextHop); !ok || p != points[1] || d.Cost < 30*time.Millisecond {
+		t.Fatalf("expected %v reached through %v in >= 30ms, got %+v", points[3], points[1], d)
+	}
+
+	dv.Install()
+	hops, err := (&Dialer{Network: network, From: ports[0]}).TraceForward(ctx, ports[3], "/1k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) < 4 || hops[len(hops)-1].Port != ports[3] {
+		t.Errorf("expected forwarded through the chain, got %v", hops)
+	}
+
+	// without poison reverse, 1 and 2 route to 3 through each other until the
+	// cost reaches infinity
+	network.Cut(Selector{City: points[3].City.Name, ISP: points[3].ISP})
+	since := network.Clock().Now()
+	elapsed = converge(t, func() bool {
+		for _, port := range ports[:3] {
+			if _, ok := dv.Vector(port)[ports[3]]; ok {
+				return false
+			}
+		}
+		return true
+	}, 30*time.Second)
+
+	var counts int
+	for _, c := range dv.Changes(since) {
+		if c.Port == ports[0] && c.Destination == ports[3] {
+			counts++
+		}
+	}
+	t.Logf("reconverged in %v with %v changes", elapsed, counts)
+	return counts
+}
@@ -0,0 +1,246 @@
+package simnet
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrDropped is returned if a transfer is dropped by packet loss.
+var ErrDropped = errors.New("dropped by packet loss")
+
+// ErrNoLink is returned if a transfer is over a link not in the affinity.
+var ErrNoLink = errors.New("no such link")
+
+// Engine is a discrete-event simulation of transfers over an affinity, which
+// works without real sockets.
+//
+// It models the same shaping as the Network does: a transfer is dropped by
+// the packet loss of link, otherwise it receives the first byte after the
+// latency, then the body at the bandwidth equally shared by all transfers on
+// the link. Connection setup is not modelled.
+type Engine struct {
+	mu    sync.Mutex
+	now   time.Duration
+	seq   int
+	queue eventQueue
+	links map[Pair]Profile
+	state map[Pair]*linkState
+	rand  *rand.Rand
+}
+
+type event struct {
+	at  time.Duration
+	seq int
+	fn  func()
+}
+
+type eventQueue []event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	return q[i].at < q[j].at || q[i].at == q[j].at && q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(event)) }
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// linkState contains transfers sharing the bandwidth of a link.
+type linkState struct {
+	flows   []*flow
+	updated time.Duration
+	version int
+}
+
+type flow struct {
+	sample    Sample
+	since     time.Duration
+	remaining float64
+	done      func(Sample, error)
+}
+
+// NewEngine creates an engine of given affinity, the seed is used to drop
+// transfers randomly.
+func NewEngine(graph Affinity, seed int64) *Engine {
+	e := &Engine{
+		links: make(map[Pair]Profile),
+		state: make(map[Pair]*linkState),
+		rand:  rand.New(rand.NewSource(seed)),
+	}
+	for _, z := range graph {
+		e.links[Pair{z.A, z.B}] = z.Profile
+	}
+	return e
+}
+
+// Now returns the simulated time since the engine is created.
+func (e *Engine) Now() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.now
+}
+
+// Transfer starts downloading a file of size bytes from b to a at the current
+// simulated time, the done is called on completion while running, and it
+// must not call methods of the engine.
+func (e *Engine) Transfer(a, b Point, size int, done func(Sample, error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.transfer(a, b, size, done)
+}
+
+// Run processes all events until no more transfers or ctx is done.
+func (e *Engine) Run(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.run(ctx, func() bool { return false })
+}
+
+// Measure implements the Measurer interface by simulating a transfer.
+func (e *Engine) Measure(ctx context.Context, a, b Point, size int) (Sample, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var (
+		s    Sample
+		err  error
+		done bool
+	)
+	e.transfer(a, b, size, func(v Sample, vErr error) {
+		s, err, done = v, vErr, true
+	})
+	if runErr := e.run(ctx, func() bool { return done }); runErr != nil {
+		return s, runErr
+	}
+	return s, err
+}
+
+func (e *Engine) transfer(a, b Point, size int, done func(Sample, error)) {
+	f := &flow{
+		sample:    Sample{A: a, B: b, Size: size},
+		since:     e.now,
+		remaining: float64(size),
+		done:      done,
+	}
+
+	// the file is downloaded from b to a, so the link is from a to b as
+	// requested by the Network
+	p, ok := e.links[Pair{a, b}]
+	if !ok {
+		e.schedule(e.now, func() { f.done(f.sample, ErrNoLink) })
+		return
+	}
+	if p.PacketLoss > 0 && e.rand.Intn(100) < p.PacketLoss {
+		e.schedule(e.now, func() { f.done(f.sample, ErrDropped) })
+		return
+	}
+
+	e.schedule(e.now+p.Latency, func() {
+		f.sample.FirstByte = e.now - f.since
+		if p.Bandwidth == 0 || size == 0 {
+			e.finish(f)
+			return
+		}
+
+		k := Pair{a, b}
+		ls := e.state[k]
+		if ls == nil {
+			ls = &linkState{updated: e.now}
+			e.state[k] = ls
+		}
+		e.settle(ls, p.Bandwidth)
+		ls.flows = append(ls.flows, f)
+		e.reschedule(k, ls, p.Bandwidth)
+	})
+}
+
+func (e *Engine) finish(f *flow) {
+	f.sample.Bytes = int64(f.sample.Size)
+	f.sample.Total = e.now - f.since
+	f.done(f.sample, nil)
+}
+
+// rate returns the bytes per second of each transfer on the link.
+func (ls *linkState) rate(bandwidth int) float64 {
+	return float64(bandwidth) * 1000 / 8 / float64(len(ls.flows))
+}
+
+// settle updates remaining bytes of transfers on the link until now.
+func (e *Engine) settle(ls *linkState, bandwidth int) {
+	if len(ls.flows) > 0 {
+		sent := ls.rate(bandwidth) * (e.now - ls.updated).Seconds()
+		for _, f := range ls.flows {
+			f.remaining -= sent
+		}
+	}
+	ls.updated = e.now
+}
+
+// reschedule schedules the next completion of transfers on the link, which
+// invalidates any previous one.
+func (e *Engine) reschedule(k Pair, ls *linkState, bandwidth int) {
+	ls.version++
+	if len(ls.flows) == 0 {
+		delete(e.state, k)
+		return
+	}
+
+	next := ls.flows[0]
+	for _, f := range ls.flows {
+		if f.remaining < next.remaining {
+			next = f
+		}
+	}
+	version := ls.version
+	d := time.Duration(next.remaining / ls.rate(bandwidth) * float64(time.Second))
+	e.schedule(e.now+d, func() {
+		if ls.version != version {
+			return
+		}
+
+		e.settle(ls, bandwidth)
+		flows := ls.flows[:0]
+		var finished []*flow
+		for _, f := range ls.flows {
+			if f == next || f.remaining <= 0 {
+				finished = append(finished, f)
+			} else {
+				flows = append(flows, f)
+			}
+		}
+		ls.flows = flows
+		e.reschedule(k, ls, bandwidth)
+		for _, f := range finished {
+			e.finish(f)
+		}
+	})
+}
+
+func (e *Engine) schedule(at time.Duration, fn func()) {
+	e.seq++
+	heap.Push(&e.queue, event{at, e.seq, fn})
+}
+
+func (e *Engine) run(ctx context.Context, stop func() bool) error {
+	for len(e.queue) > 0 && !stop() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ev := heap.Pop(&e.queue).(event)
+		e.now = ev.at
+		ev.fn()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package simnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEngine(t *testing.T) {
+	a := Point{City: cities["北京市"], ISP: 1}
+	b := Point{City: cities["上海市"], ISP: 1}
+	c := Point{City: cities["广州市"], ISP: 1}
+	graph := Affinity{
+		{A: a, B: b, Profile: Profile{Latency: 50 * time.Millisecond, Bandwidth: 800}},
+		{A: a, B: c, Profile: Profile{PacketLoss: 100}},
+	}
+	ctx := context.Background()
+
+	t.Run("Measure", func(t *testing.T) {
+		e := NewEngine(graph, 1)
+		s, err := e.Measure(ctx, a, b, 100*1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.FirstByte != 50*time.Millisecond {
+			t.Errorf("expected first byte after 50ms, got %v", s.FirstByte)
+		}
+		if s.Total != 1050*time.Millisecond {
+			t.Errorf("expected total 1.05s, got %v", s.Total)
+		}
+		if v := s.Throughput(); v < 799 || v > 801 {
+			t.Errorf("expected throughput 800kbit/s, got %v", v)
+		}
+
+		if _, err := e.Measure(ctx, a, c, 1000); err != ErrDropped {
+			t.Errorf("expected %v, got %v", ErrDropped, err)
+		}
+		if _, err := e.Measure(ctx, b, c, 1000); err != ErrNoLink {
+			t.Errorf("expected %v, got %v", ErrNoLink, err)
+		}
+	})
+
+	t.Run("Bandwidth sharing", func(t *testing.T) {
+		e := NewEngine(graph, 1)
+		var totals []time.Duration
+		done := func(s Sample, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			totals = append(totals, s.Total)
+		}
+		e.Transfer(a, b, 100*1000, done)
+		e.Transfer(a, b, 50*1000, done)
+		if err := e.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// both share 800kbit/s until the smaller one finishes at 1s, then
+		// the rest 50k goes at full speed in 0.5s
+		expected := []time.Duration{1050 * time.Millisecond, 1550 * time.Millisecond}
+		if len(totals) != 2 || totals[0] != expected[0] || totals[1] != expected[1] {
+			t.Errorf("expected %v, got %v", expected, totals)
+		}
+		if now := e.Now(); now != 1550*time.Millisecond {
+			t.Errorf("expected simulated time 1.55s, got %v", now)
+		}
+	})
+}
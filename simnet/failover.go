@@ -0,0 +1,219 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FailoverEvent is a switch of routes by the failover client.
+type FailoverEvent struct {
+	Time time.Time
+	// From and To are indices of routes switched from and to, To is -1 if
+	// no route is available.
+	From, To int
+	// Reason is the error of the route switched from, or empty if switching
+	// back after the hold-down.
+	Reason string
+	// Detection is the time spent on detecting the failure.
+	Detection time.Duration
+}
+
+// Failover sends requests of a dialer through precomputed routes in order of
+// preference, it switches to the next route once the current one fails.
+type Failover struct {
+	Dialer *Dialer
+	// Routes are the routes in order of preference, e.g. the primary and
+	// backup of disjoint paths.
+	Routes [][]int
+	// Timeout is the time waiting for the response before a route is
+	// regarded as failed, 1s if non-positive.
+	Timeout time.Duration
+	// HoldDown is the duration a failed route is not used, after which it is
+	// preferred again, 10s if non-positive.
+	HoldDown time.Duration
+
+	mu      sync.Mutex
+	current int
+	failed  map[int]time.Time
+	events  []FailoverEvent
+}
+
+func (f *Failover) timeout() time.Duration {
+	if f.Timeout <= 0 {
+		return time.Second
+	}
+	return f.Timeout
+}
+
+func (f *Failover) holdDown() time.Duration {
+	if f.HoldDown <= 0 {
+		return 10 * time.Second
+	}
+	return f.HoldDown
+}
+
+// Current returns the index of current route.
+func (f *Failover) Current() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current
+}
+
+// Events returns all switches in order.
+func (f *Failover) Events() []FailoverEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FailoverEvent(nil), f.events...)
+}
+
+// available reports whether the route is not held down at now, the lock
+// must be held.
+func (f *Failover) available(i int, now time.Time) bool {
+	t, ok := f.failed[i]
+	return !ok || now.Sub(t) >= f.holdDown()
+}
+
+// pick returns the most preferred route neither held down nor tried, or -1
+// if none.
+func (f *Failover) pick(now time.Time, tried map[int]bool) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.Routes {
+		if tried[i] || !f.available(i, now) {
+			continue
+		}
+		delete(f.failed, i)
+		if i != f.current {
+			f.events = append(f.events, FailoverEvent{Time: now, From: f.current, To: i})
+			f.current = i
+		}
+		return i
+	}
+	return -1
+}
+
+// fail marks the route failed, then records the switch to the next route
+// neither held down nor tried.
+func (f *Failover) fail(i int, err error, since, now time.Time, tried map[int]bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failed == nil {
+		f.failed = make(map[int]time.Time)
+	}
+	f.failed[i] = now
+
+	next := -1
+	for j := range f.Routes {
+		if !tried[j] && f.available(j, now) {
+			next = j
+			break
+		}
+	}
+	f.events = append(f.events, FailoverEvent{now, i, next, err.Error(), now.Sub(since)})
+	if next >= 0 {
+		f.current = next
+	}
+}
+
+// Get requests the path through the current route, it fails over to next
+// routes until a response is received, or all routes fail. Each route is
+// tried at most once. A route reset while reading the body is marked failed
+// as well, so the next request fails over.
+func (f *Failover) Get(ctx context.Context, path string) (*http.Response, error) {
+	clock := f.Dialer.Network.Clock()
+	tried := make(map[int]bool)
+	err := fmt.Errorf("no routes")
+	for {
+		i := f.pick(clock.Now(), tried)
+		if i < 0 {
+			return nil, err
+		}
+		tried[i] = true
+
+		since := clock.Now()
+		var res *http.Response
+		res, err = f.get(ctx, f.Routes[i], path)
+		if err == nil {
+			body := res.Body.(*cancelBody)
+			body.fail = func(err error) {
+				f.fail(i, err, since, clock.Now(), nil)
+			}
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		f.fail(i, err, since, clock.Now(), tried)
+	}
+}
+
+// get requests the path through the route, it's canceled if no response in
+// time, and a response of server errors is regarded as failure.
+func (f *Failover) get(ctx context.Context, route []int, path string) (*http.Response, error) {
+	clock := f.Dialer.Network.Clock()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	timedOut := make(chan bool, 1)
+	go func() {
+		select {
+		case <-clock.After(f.timeout()):
+			timedOut <- true
+			cancel()
+		case <-done:
+			timedOut <- false
+		}
+	}()
+
+	res, err := f.Dialer.Get(ctx, route, path)
+	close(done)
+	if <-timedOut {
+		if err == nil {
+			res.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("timeout after %v", f.timeout())
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if res.StatusCode >= 500 {
+		res.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, ctx: ctx, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody cancels the request once closed, and calls the fail once if
+// reading is failed before closed.
+type cancelBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+	fail   func(error)
+	once   sync.Once
+}
+
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.ctx.Err() == nil && b.fail != nil {
+		b.once.Do(func() { b.fail(err) })
+	}
+	return n, err
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
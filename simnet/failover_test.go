@@ -0,0 +1,141 @@
+package simnet
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	network, points, ports := diamondNetwork(t, Profile{Latency: 5 * time.Millisecond})
+	defer network.Close()
+
+	f := &Failover{
+		Dialer:   &Dialer{Network: network, From: ports[0]},
+		Routes:   [][]int{{ports[1], ports[3]}, {ports[2], ports[3]}},
+		Timeout:  200 * time.Millisecond,
+		HoldDown: 300 * time.Millisecond,
+	}
+	get := func() {
+		res, err := f.Get(context.Background(), "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	get()
+	if f.Current() != 0 || len(f.Events()) != 0 {
+		t.Fatalf("expected the primary route, got %v", f.Events())
+	}
+
+	t.Run("Reset", func(t *testing.T) {
+		network.Cut(Selector{City: points[1].City.Name, ISP: points[1].ISP})
+		get()
+		events := f.Events()
+		if f.Current() != 1 || len(events) != 1 || events[0].From != 0 || events[0].To != 1 {
+			t.Errorf("expected failover to the backup, got %v", events)
+		}
+		if events[0].Detection >= f.Timeout {
+			t.Errorf("expected reset detected before timeout, got %v", events[0].Detection)
+		}
+
+		network.Restore(Selector{City: points[1].City.Name, ISP: points[1].ISP})
+		get()
+		if f.Current() != 1 {
+			t.Errorf("expected held down")
+		}
+		time.Sleep(f.HoldDown)
+		get()
+		if events := f.Events(); f.Current() != 0 || len(events) != 2 || events[1].Reason != "" {
+			t.Errorf("expected switching back to the primary, got %v", events)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		network.SetProfile(points[1], points[3], Profile{Latency: time.Second})
+		since := time.Now()
+		get()
+		if elapsed := time.Since(since); elapsed > 500*time.Millisecond {
+			t.Errorf("expected recovered in 500ms, got %v", elapsed)
+		}
+		events := f.Events()
+		if e := events[len(events)-1]; f.Current() != 1 || e.Detection < f.Timeout {
+			t.Errorf("expected failover after timeout, got %+v", e)
+		}
+	})
+
+	t.Run("All failed", func(t *testing.T) {
+		network.Cut(Selector{City: points[3].City.Name, ISP: points[3].ISP})
+		defer network.Restore(Selector{City: points[3].City.Name, ISP: points[3].ISP})
+		if _, err := f.Get(context.Background(), "/1k"); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+		if e := f.Events(); e[len(e)-1].To != -1 {
+			t.Errorf("expected no route available, got %+v", e[len(e)-1])
+		}
+	})
+}
+
+func TestFailoverTried(t *testing.T) {
+	network, points, ports := diamondNetwork(t, Profile{Latency: 5 * time.Millisecond})
+	defer network.Close()
+
+	// the hold-down expires before the failure is detected, but the failed
+	// route is not retried by the same request
+	f := &Failover{
+		Dialer:   &Dialer{Network: network, From: ports[0]},
+		Routes:   [][]int{{ports[1], ports[3]}, {ports[2], ports[3]}},
+		HoldDown: time.Nanosecond,
+	}
+	network.Cut(Selector{City: points[1].City.Name, ISP: points[1].ISP})
+	res, err := f.Get(context.Background(), "/1k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if events := f.Events(); f.Current() != 1 || len(events) != 1 || events[0].To != 1 {
+		t.Errorf("expected failover to the backup, got %v", events)
+	}
+
+	network.Cut(Selector{City: points[2].City.Name, ISP: points[2].ISP})
+	if _, err := f.Get(context.Background(), "/1k"); err == nil {
+		t.Errorf("expected an error, got nil")
+	}
+	if e := f.Events(); e[len(e)-1].To != -1 {
+		t.Errorf("expected no route available, got %+v", e[len(e)-1])
+	}
+}
+
+func TestFailoverBody(t *testing.T) {
+	network, _, ports := diamondNetwork(t, Profile{Latency: 5 * time.Millisecond})
+	defer network.Close()
+
+	network.Handle("/reset", func(port int, w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	})
+	f := &Failover{
+		Dialer: &Dialer{Network: network, From: ports[0]},
+		Routes: [][]int{{ports[1], ports[3]}, {ports[2], ports[3]}},
+	}
+	res, err := f.Get(context.Background(), "/reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if err == nil {
+		t.Fatal("expected reset while reading, got nil")
+	}
+
+	events := f.Events()
+	if f.Current() != 1 || len(events) != 1 || events[0].From != 0 || events[0].To != 1 || events[0].Reason == "" {
+		t.Errorf("expected failover to the backup, got %v", events)
+	}
+}
@@ -0,0 +1,119 @@
+package simnet
+
+import (
+	"sort"
+	"time"
+)
+
+// Without returns a copy of graph without selected points and their links.
+func (g *Graph) Without(s Selector) *Graph {
+	r := &Graph{edges: make(map[Point]map[Point]Profile)}
+	for _, a := range g.points {
+		if s.Match(a) {
+			continue
+		}
+		r.add(a)
+		for b, p := range g.edges[a] {
+			if !s.Match(b) {
+				r.edges[a][b] = p
+			}
+		}
+	}
+	return r
+}
+
+// Impact is the impact of a failure on routes between surviving points.
+type Impact struct {
+	// Failure selects failed points.
+	Failure Selector
+	Failed  []Point
+	// Lost are ordered pairs routed before but not after the failure.
+	Lost []Pair
+	// Rerouted is the number of pairs whose routes change.
+	Rerouted int
+	// Added is the total latency added to rerouted pairs.
+	Added time.Duration
+	// Routes is the degraded route table.
+	Routes RouteTable
+}
+
+// more reports whether i has more impact than o, by lost pairs then added
+// latency.
+func (i *Impact) more(o *Impact) bool {
+	return len(i.Lost) > len(o.Lost) || len(i.Lost) == len(o.Lost) && i.Added > o.Added
+}
+
+// WhatIf computes the impact of failing selected points on routes by the
+// policy, e.g. Selector{City: "上海市", ISP: 1} for the core of ISP 1 in
+// 上海市 is down.
+func WhatIf(g *Graph, policy Policy, failure Selector) *Impact {
+	return whatIf(g, policy, Routes(g, policy), failure)
+}
+
+func whatIf(g *Graph, policy Policy, routes RouteTable, failure Selector) *Impact {
+	degraded := g.Without(failure)
+	i := &Impact{Failure: failure, Routes: Routes(degraded, policy)}
+	for _, p := range g.Points() {
+		if failure.Match(p) {
+			i.Failed = append(i.Failed, p)
+		}
+	}
+
+	for _, a := range degraded.Points() {
+		for _, b := range degraded.Points() {
+			before, ok := routes[Pair{a, b}]
+			if !ok {
+				continue
+			}
+			after, ok := i.Routes[Pair{a, b}]
+			if !ok {
+				i.Lost = append(i.Lost, Pair{a, b})
+				continue
+			}
+			if !samePath(before, after) {
+				x, _ := g.Profile(before)
+				y, _ := g.Profile(after)
+				i.Rerouted++
+				i.Added += y.Latency - x.Latency
+			}
+		}
+	}
+	return i
+}
+
+func samePath(a, b Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RankFailures computes impacts of failing each point and each cell of
+// points of the same ISP within a province, it returns ones losing pairs or
+// adding latency in order of impact.
+func RankFailures(g *Graph, policy Policy) []*Impact {
+	cells := make(map[Selector]int)
+	var failures []Selector
+	for _, p := range g.Points() {
+		failures = append(failures, Selector{City: p.City.Name, ISP: p.ISP})
+		s := Selector{Province: p.City.Province, ISP: p.ISP}
+		if cells[s]++; cells[s] == 2 {
+			failures = append(failures, s)
+		}
+	}
+
+	routes := Routes(g, policy)
+	var impacts []*Impact
+	for _, s := range failures {
+		if i := whatIf(g, policy, routes, s); len(i.Lost) > 0 || i.Added > 0 {
+			impacts = append(impacts, i)
+		}
+	}
+	sort.SliceStable(impacts, func(i, j int) bool { return impacts[i].more(impacts[j]) })
+	return impacts
+}
@@ -0,0 +1,75 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWhatIf(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["昆明市"], ISP: 1}
+	c := Point{City: cities["南京市"], ISP: 1}
+	x := Point{City: cities["上海市"], ISP: 1}
+	y := Point{City: cities["北京市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: x, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: a, B: y, Profile: Profile{Latency: 20 * time.Millisecond}},
+		{A: x, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: y, B: b, Profile: Profile{Latency: 20 * time.Millisecond}},
+		{A: c, B: x, Profile: Profile{Latency: 10 * time.Millisecond}},
+	})
+
+	i := WhatIf(g, ShortestPath{}, Selector{City: "上海市"})
+	if len(i.Failed) != 1 || i.Failed[0] != x {
+		t.Errorf("expected %v failed, got %v", x, i.Failed)
+	}
+	if len(i.Lost) != 1 || i.Lost[0] != (Pair{c, b}) {
+		t.Errorf("expected %v to %v lost, got %v", c, b, i.Lost)
+	}
+	if i.Rerouted != 1 || i.Added != 20*time.Millisecond {
+		t.Errorf("expected 1 route added 20ms, got %v added %v", i.Rerouted, i.Added)
+	}
+	if path := i.Routes[Pair{a, b}]; len(path) != 3 || path[1] != y {
+		t.Errorf("expected degraded route through %v, got %v", y, path)
+	}
+
+	t.Run("Rank", func(t *testing.T) {
+		impacts := RankFailures(g, ShortestPath{})
+		if len(impacts) != 1 || impacts[0].Failure != (Selector{City: "上海市", ISP: 1}) {
+			t.Errorf("expected only 上海市 impacting, got %v", impacts)
+		}
+	})
+
+	t.Run("Model", func(t *testing.T) {
+		var points []Point
+		for _, name := range names {
+			if Tier(name) <= 3 {
+				for i := 0; i < 2; i++ {
+					points = append(points, Point{City: cities[name], ISP: isps[i]})
+				}
+			}
+		}
+
+		impacts := RankFailures(NewGraph(NewAffinity(points)), TierTree{})
+		for i, v := range impacts {
+			if i > 0 && v.more(impacts[i-1]) {
+				t.Errorf("expected impacts in order")
+			}
+		}
+
+		// cities other than cores are reachable only within the province, so
+		// the core of 广东省 relays all other provinces to the rest of it
+		core := Point{City: cities["广州市"], ISP: 1}
+		points = []Point{core}
+		for _, name := range []string{"深圳市", "珠海市", "南京市", "杭州市", "上海市", "北京市"} {
+			points = append(points, Point{City: cities[name], ISP: 1})
+		}
+		i := WhatIf(NewGraph(NewAffinity(points)), TierTree{}, Selector{City: "广州市"})
+		if len(i.Failed) != 1 || i.Failed[0] != core {
+			t.Errorf("expected %v failed, got %v", core, i.Failed)
+		}
+		if len(i.Lost) != 8 {
+			t.Errorf("expected 8 pairs from 4 cities to 深圳市 and 珠海市 lost, got %v", i.Lost)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package simnet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// InferAffinity builds an affinity from measured results. The packet loss is
+// the ratio of failed samples, the latency is the time to first byte after
+// connected, and the bandwidth is the mean throughput.
+func InferAffinity(m Matrix) Affinity {
+	r := make(Affinity, 0, len(m))
+	for k, v := range m {
+		z := Link{A: k.A, B: k.B}
+		z.PacketLoss = int(math.Round((1 - v.SuccessRate()) * 100))
+		if v.Failures < v.Samples {
+			z.Latency = v.FirstByte - v.Connect
+			z.Bandwidth = int(math.Round(v.Throughput))
+		}
+		r = append(r, z)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return lessPoint(r[i].A, r[j].A) || r[i].A == r[j].A && lessPoint(r[i].B, r[j].B)
+	})
+	return r
+}
+
+// lessPoint orders points by city and ISP.
+func lessPoint(a, b Point) bool {
+	return a.City.ID < b.City.ID || a.City.ID == b.City.ID && a.ISP < b.ISP
+}
+
+// lessPair orders pairs by the start then the end.
+func lessPair(a, b Pair) bool {
+	if a.A != b.A {
+		return lessPoint(a.A, b.A)
+	}
+	return lessPoint(a.B, b.B)
+}
+
+// measurementColumns are required columns of ReadMeasurements.
+var measurementColumns = []string{
+	"city_a", "isp_a", "city_b", "isp_b",
+	"transmitted", "received", "rtt_ms", "bandwidth_kbps",
+}
+
+// ReadMeasurements reads measured results from CSV, which are usually
+// converted from ping and iperf logs of real IDCs. The first row is a header
+// containing the columns below in any order.
+//
+//	city_a, isp_a   - the point where measurements are taken
+//	city_b, isp_b   - the measured point
+//	transmitted     - the number of transmitted ping packets
+//	received        - the number of received ping packets
+//	rtt_ms          - the average round-trip time in milliseconds by ping
+//	bandwidth_kbps  - the bandwidth in kbit/s by iperf, 0 if unknown
+func ReadMeasurements(r io.Reader) (Matrix, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	index := make(map[string]int)
+	for i, name := range records[0] {
+		index[name] = i
+	}
+	for _, name := range measurementColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing column: %v", name)
+		}
+	}
+
+	m := make(Matrix)
+	for i, record := range records[1:] {
+		line := i + 2
+		field := func(name string) string { return record[index[name]] }
+		number := func(name string) float64 {
+			if err != nil {
+				return 0
+			}
+			var v float64
+			v, err = strconv.ParseFloat(field(name), 64)
+			if err != nil {
+				err = fmt.Errorf("line %v: invalid %v: %v", line, name, err)
+			}
+			return v
+		}
+
+		a, ok := cities[field("city_a")]
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown city: %v", line, field("city_a"))
+		}
+		b, ok := cities[field("city_b")]
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown city: %v", line, field("city_b"))
+		}
+
+		k := Pair{Point{a, ISP(number("isp_a"))}, Point{b, ISP(number("isp_b"))}}
+		transmitted, received := int(number("transmitted")), int(number("received"))
+		rtt, bandwidth := number("rtt_ms"), number("bandwidth_kbps")
+		if err != nil {
+			return nil, err
+		}
+		if received > transmitted {
+			return nil, fmt.Errorf("line %v: received more than transmitted", line)
+		}
+
+		m[k] = &Result{
+			A:          k.A,
+			B:          k.B,
+			Samples:    transmitted,
+			Failures:   transmitted - received,
+			FirstByte:  time.Duration(rtt * float64(time.Millisecond)),
+			Throughput: bandwidth,
+		}
+	}
+	return m, nil
+}
+
+// IsolationCorrelation returns the Pearson correlation coefficient between
+// Isolate and the latency of reachable links, which tells how well Isolate
+// estimates the affinity.
+func IsolationCorrelation(graph Affinity) float64 {
+	var xs, ys []float64
+	for _, z := range graph {
+		if z.PacketLoss < 100 {
+			xs = append(xs, Isolate(z.A, z.B))
+			ys = append(ys, z.Latency.Seconds())
+		}
+	}
+	return correlation(xs, ys)
+}
+
+func correlation(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,81 @@
+package simnet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferAffinity(t *testing.T) {
+	var points []Point
+	for i := 0; i < 30; i++ {
+		points = append(points, NewPoint(i*7))
+	}
+	graph := NewAffinity(points)
+	p := &Prober{Measurer: NewEngine(graph, 1), Size: 1024, Samples: 10}
+	m, err := p.Probe(context.Background(), points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inferred := InferAffinity(m)
+	if len(inferred) != len(graph) {
+		t.Fatalf("expected %v links, got %v", len(graph), len(inferred))
+	}
+	profiles := make(map[Pair]Profile)
+	for _, z := range graph {
+		profiles[Pair{z.A, z.B}] = z.Profile
+	}
+	for _, z := range inferred {
+		expected := profiles[Pair{z.A, z.B}]
+		if expected.PacketLoss == 100 && z.PacketLoss != 100 || expected.PacketLoss == 0 && z.PacketLoss != 0 {
+			t.Errorf("expected %v%% packet loss from %v to %v, got %v%%", expected.PacketLoss, z.A, z.B, z.PacketLoss)
+		}
+		if z.PacketLoss < 100 && z.Latency != expected.Latency {
+			t.Errorf("expected latency %v from %v to %v, got %v", expected.Latency, z.A, z.B, z.Latency)
+		}
+	}
+
+	if c := IsolationCorrelation(graph); c < 0.99 {
+		t.Errorf("expected correlation of model about 1, got %v", c)
+	}
+}
+
+func TestReadMeasurements(t *testing.T) {
+	m, err := ReadMeasurements(strings.NewReader(`city_a,isp_a,city_b,isp_b,transmitted,received,rtt_ms,bandwidth_kbps
+北京市,0.5,上海市,-0.5,10,9,30.5,10000
+上海市,-0.5,北京市,0.5,10,0,0,0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := Point{cities["北京市"], 0.5}
+	b := Point{cities["上海市"], -0.5}
+	r := m[Pair{a, b}]
+	if r == nil || r.Samples != 10 || r.Failures != 1 || r.FirstByte != 30500*time.Microsecond || r.Throughput != 10000 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	for _, z := range InferAffinity(m) {
+		if z.A == a && z.PacketLoss != 10 {
+			t.Errorf("expected 10%% packet loss, got %v", z.PacketLoss)
+		}
+		if z.A == b && z.PacketLoss != 100 {
+			t.Errorf("expected 100%% packet loss, got %v", z.PacketLoss)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"city_a,isp_a\n",
+		"city_a,isp_a,city_b,isp_b,transmitted,received,rtt_ms,bandwidth_kbps\n北平市,0,上海市,0,1,1,1,1\n",
+		"city_a,isp_a,city_b,isp_b,transmitted,received,rtt_ms,bandwidth_kbps\n北京市,x,上海市,0,1,1,1,1\n",
+		"city_a,isp_a,city_b,isp_b,transmitted,received,rtt_ms,bandwidth_kbps\n北京市,0,上海市,0,1,2,1,1\n",
+	} {
+		if _, err := ReadMeasurements(strings.NewReader(s)); err == nil {
+			t.Errorf("expected an error of %q, got nil", s)
+		}
+	}
+}
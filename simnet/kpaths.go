@@ -0,0 +1,118 @@
+package simnet
+
+import "sort"
+
+// Route is a path with the composed profile along it.
+type Route struct {
+	Path    Path
+	Profile Profile
+}
+
+func (g *Graph) route(path Path) Route {
+	p, _ := g.Profile(path)
+	return Route{Path: path, Profile: p}
+}
+
+// KShortestPaths returns at most k loopless paths from a to b in order of
+// latency by Yen's algorithm.
+func (g *Graph) KShortestPaths(a, b Point, k int) []Route {
+	first, ok := g.ShortestPaths(a)[b]
+	if !ok || k < 1 || a == b {
+		return nil
+	}
+
+	routes := []Route{g.route(first)}
+	var candidates []Route
+	for len(routes) < k {
+		last := routes[len(routes)-1].Path
+		for i := 0; i < len(last)-1; i++ {
+			spur, root := last[i], last[:i+1]
+
+			removed := make(map[Pair]bool)
+			for _, r := range routes {
+				if len(r.Path) > i+1 && samePath(r.Path[:i+1], root) {
+					removed[Pair{r.Path[i], r.Path[i+1]}] = true
+				}
+			}
+			excluded := make(map[Point]bool)
+			for _, p := range root[:i] {
+				excluded[p] = true
+			}
+
+			tail, ok := g.shortestPaths(spur, func(x, y Point) bool {
+				return removed[Pair{x, y}] || excluded[y]
+			})[b]
+			if !ok {
+				continue
+			}
+
+			path := append(append(Path(nil), root[:i]...), tail...)
+			if !containsPath(routes, path) && !containsPath(candidates, path) {
+				candidates = append(candidates, g.route(path))
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Profile.Latency < candidates[j].Profile.Latency
+		})
+		routes = append(routes, candidates[0])
+		candidates = candidates[1:]
+	}
+	return routes
+}
+
+func containsPath(routes []Route, path Path) bool {
+	for _, r := range routes {
+		if samePath(r.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Disjointness is how two paths between the same ends are disjoint.
+type Disjointness int
+
+const (
+	// NodeDisjoint paths share no intermediate points.
+	NodeDisjoint Disjointness = iota
+	// ISPDisjoint paths share no ISPs of intermediate points.
+	ISPDisjoint
+)
+
+// disjoint reports whether two paths are disjoint.
+func (d Disjointness) disjoint(x, y Path) bool {
+	for _, a := range x[1 : len(x)-1] {
+		for _, b := range y[1 : len(y)-1] {
+			if a == b || d == ISPDisjoint && a.ISP == b.ISP {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// DisjointPaths returns the primary and backup routes from a to b which are
+// disjoint and of the least total latency, where candidates are the k
+// shortest paths, and whether such a pair exists.
+func (g *Graph) DisjointPaths(a, b Point, k int, d Disjointness) (Route, Route, bool) {
+	routes := g.KShortestPaths(a, b, k)
+	x, y := -1, -1
+	for i := range routes {
+		for j := i + 1; j < len(routes); j++ {
+			if !d.disjoint(routes[i].Path, routes[j].Path) {
+				continue
+			}
+			if x < 0 || routes[i].Profile.Latency+routes[j].Profile.Latency < routes[x].Profile.Latency+routes[y].Profile.Latency {
+				x, y = i, j
+			}
+		}
+	}
+	if x < 0 {
+		return Route{}, Route{}, false
+	}
+	return routes[x], routes[y], true
+}
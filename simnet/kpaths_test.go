@@ -0,0 +1,57 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKShortestPaths(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["昆明市"], ISP: 1}
+	x := Point{City: cities["上海市"], ISP: 1}
+	y := Point{City: cities["上海市"], ISP: 2}
+	z := Point{City: cities["北京市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: x, Profile: Profile{Latency: 10 * time.Millisecond, PacketLoss: 10}},
+		{A: x, B: b, Profile: Profile{Latency: 10 * time.Millisecond, PacketLoss: 10}},
+		{A: a, B: y, Profile: Profile{Latency: 15 * time.Millisecond}},
+		{A: y, B: b, Profile: Profile{Latency: 15 * time.Millisecond}},
+		{A: x, B: z, Profile: Profile{Latency: 5 * time.Millisecond}},
+		{A: a, B: z, Profile: Profile{Latency: 30 * time.Millisecond}},
+		{A: z, B: b, Profile: Profile{Latency: 5 * time.Millisecond}},
+	})
+
+	routes := g.KShortestPaths(a, b, 10)
+	if len(routes) != 4 {
+		t.Fatalf("expected 4 paths, got %v", routes)
+	}
+	for i, want := range []time.Duration{20, 20, 30, 35} {
+		if routes[i].Profile.Latency != want*time.Millisecond {
+			t.Errorf("expected path %v of %vms, got %v", i, want, routes[i].Profile.Latency)
+		}
+	}
+	if p := routes[0].Profile; p.PacketLoss != 19 {
+		t.Errorf("expected 19%% loss of %v, got %v", routes[0].Path, p.PacketLoss)
+	}
+	if routes := g.KShortestPaths(a, b, 2); len(routes) != 2 {
+		t.Errorf("expected 2 paths, got %v", routes)
+	}
+	if routes := g.KShortestPaths(b, a, 2); len(routes) != 0 {
+		t.Errorf("expected no paths, got %v", routes)
+	}
+
+	t.Run("Disjoint", func(t *testing.T) {
+		primary, backup, ok := g.DisjointPaths(a, b, 10, NodeDisjoint)
+		if !ok || primary.Profile.Latency+backup.Profile.Latency != 50*time.Millisecond {
+			t.Errorf("expected node disjoint paths of 50ms, got %v and %v", primary, backup)
+		}
+
+		primary, backup, ok = g.DisjointPaths(a, b, 10, ISPDisjoint)
+		if !ok || primary.Path[1] != y && backup.Path[1] != y {
+			t.Errorf("expected a path through %v, got %v and %v", y, primary, backup)
+		}
+		if _, _, ok := g.DisjointPaths(a, b, 1, NodeDisjoint); ok {
+			t.Errorf("expected no disjoint pair of a single path")
+		}
+	})
+}
@@ -0,0 +1,489 @@
+package simnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LSA is a link-state advertisement, which carries costs of links from the
+// origin server to its neighbors.
+type LSA struct {
+	Origin   int
+	Sequence int
+	Links    map[int]time.Duration
+}
+
+// LSAInstall is an installation of a newer LSA into the database of server.
+type LSAInstall struct {
+	Time     time.Time
+	Port     int
+	Origin   int
+	Sequence int
+}
+
+// LinkState runs a link-state routing protocol among all servers of network,
+// where each server periodically probes every other server by GET /hello,
+// the smoothed round-trip time of which is the cost of link, and originates
+// an LSA once the links change. LSAs are flooded to neighbors by POST /lsa,
+// then each server computes shortest paths by its own database.
+type LinkState struct {
+	Network *Network
+	// Period is the interval of probes, 1s if non-positive.
+	Period time.Duration
+	// Timeout is the timeout of each probe or flooding, Period if
+	// non-positive.
+	Timeout time.Duration
+	// Tolerance is the maximum change of link cost which doesn't originate a
+	// new LSA, so that jitters of round-trip times are ignored.
+	Tolerance time.Duration
+
+	mu        sync.Mutex
+	databases map[int]map[int]LSA
+	neighbors map[int]map[int]time.Duration
+	installs  []LSAInstall
+	messages  int
+}
+
+func (ls *LinkState) period() time.Duration {
+	if ls.Period <= 0 {
+		return time.Second
+	}
+	return ls.Period
+}
+
+func (ls *LinkState) timeout() time.Duration {
+	if ls.Timeout <= 0 {
+		return ls.period()
+	}
+	return ls.Timeout
+}
+
+// Run runs the protocol on all servers until ctx is done.
+func (ls *LinkState) Run(ctx context.Context) error {
+	ports := ls.Network.Ports()
+	ls.mu.Lock()
+	ls.databases = make(map[int]map[int]LSA, len(ports))
+	ls.neighbors = make(map[int]map[int]time.Duration, len(ports))
+	for _, port := range ports {
+		ls.databases[port] = map[int]LSA{port: {Origin: port}}
+		ls.neighbors[port] = make(map[int]time.Duration)
+	}
+	ls.mu.Unlock()
+
+	ls.Network.Handle("/hello", func(int, http.ResponseWriter, *http.Request) {})
+	ls.Network.Handle("/lsa", func(port int, w http.ResponseWriter, r *http.Request) {
+		ls.receive(ctx, port, w, r)
+	})
+	defer ls.Network.Handle("/hello", nil)
+	defer ls.Network.Handle("/lsa", nil)
+
+	var wg sync.WaitGroup
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			ls.run(ctx, port, ports)
+		}(port)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (ls *LinkState) run(ctx context.Context, port int, ports []int) {
+	clock := ls.Network.Clock()
+	for {
+		select {
+		case <-clock.After(ls.period()):
+		case <-ctx.Done():
+			return
+		}
+
+		var (
+			mu    sync.Mutex
+			wg    sync.WaitGroup
+			links = make(map[int]time.Duration)
+		)
+		old := ls.links(port)
+		for _, to := range ports {
+			if to == port {
+				continue
+			}
+			wg.Add(1)
+			go func(to int) {
+				defer wg.Done()
+				since := clock.Now()
+				err := ls.send(ctx, port, to, "GET", "/hello", nil)
+				rtt := clock.Now().Sub(since)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					return
+				}
+				if cost, ok := old[to]; ok {
+					links[to] = cost + (rtt-cost)/8
+				} else {
+					links[to] = rtt
+				}
+			}(to)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		ls.mu.Lock()
+		ls.neighbors[port] = links
+		self := ls.databases[port][port]
+		changed := len(links) != len(self.Links)
+		for to, cost := range links {
+			x, ok := self.Links[to]
+			changed = changed || !ok || cost-x > ls.Tolerance || x-cost > ls.Tolerance
+		}
+		var lsa LSA
+		if changed {
+			lsa = LSA{Origin: port, Sequence: self.Sequence + 1, Links: links}
+			ls.install(port, lsa, clock.Now())
+		}
+		var added []int
+		for to := range links {
+			if _, ok := old[to]; !ok {
+				added = append(added, to)
+			}
+		}
+		ls.mu.Unlock()
+
+		if changed {
+			ls.flood(ctx, port, -1, lsa)
+		}
+		// synchronizes the whole database with new neighbors
+		for _, to := range added {
+			for _, v := range ls.Database(port) {
+				go ls.send(ctx, port, to, "POST", "/lsa", v)
+			}
+		}
+	}
+}
+
+// links returns the current costs of links from server to its neighbors.
+func (ls *LinkState) links(port int) map[int]time.Duration {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	links := make(map[int]time.Duration, len(ls.neighbors[port]))
+	for to, cost := range ls.neighbors[port] {
+		links[to] = cost
+	}
+	return links
+}
+
+// install installs the LSA if newer, the lock must be held.
+func (ls *LinkState) install(port int, lsa LSA, now time.Time) bool {
+	if old, ok := ls.databases[port][lsa.Origin]; ok && old.Sequence >= lsa.Sequence {
+		return false
+	}
+	ls.databases[port][lsa.Origin] = lsa
+	ls.installs = append(ls.installs, LSAInstall{now, port, lsa.Origin, lsa.Sequence})
+	return true
+}
+
+// receive installs the LSA from a neighbor, then floods it if newer.
+func (ls *LinkState) receive(ctx context.Context, port int, w http.ResponseWriter, r *http.Request) {
+	var lsa LSA
+	if err := json.NewDecoder(r.Body).Decode(&lsa); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, _ := strconv.Atoi(r.Header.Get(FromHeader))
+
+	ls.mu.Lock()
+	newer := ls.install(port, lsa, ls.Network.Clock().Now())
+	ls.mu.Unlock()
+	if newer {
+		go ls.flood(ctx, port, from, lsa)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// flood sends the LSA to all neighbors of server except the sender.
+func (ls *LinkState) flood(ctx context.Context, port, sender int, lsa LSA) {
+	var wg sync.WaitGroup
+	for to := range ls.links(port) {
+		if to == sender || to == lsa.Origin {
+			continue
+		}
+		wg.Add(1)
+		go func(to int) {
+			defer wg.Done()
+			ls.send(ctx, port, to, "POST", "/lsa", lsa)
+		}(to)
+	}
+	wg.Wait()
+}
+
+// send requests the path of server on port to from server on port from, with
+// the JSON body if non-nil.
+func (ls *LinkState) send(ctx context.Context, from, to int, method, path string, body interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, ls.timeout())
+	defer cancel()
+
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+		ls.mu.Lock()
+		ls.messages++
+		ls.mu.Unlock()
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", to, path), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(FromHeader, fmt.Sprint(from))
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// Database returns LSAs in the database of server on given port.
+func (ls *LinkState) Database(port int) []LSA {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	var lsas []LSA
+	for _, lsa := range ls.databases[port] {
+		lsas = append(lsas, lsa)
+	}
+	return lsas
+}
+
+// Messages returns the number of LSAs sent, including duplicates.
+func (ls *LinkState) Messages() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	return ls.messages
+}
+
+// Installs returns installations of LSAs since given time in order.
+func (ls *LinkState) Installs(since time.Time) []LSAInstall {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	var installs []LSAInstall
+	for _, i := range ls.installs {
+		if !i.Time.Before(since) {
+			installs = append(installs, i)
+		}
+	}
+	return installs
+}
+
+// Converged returns the time of the last installation, and whether databases
+// of all servers having neighbors are synchronized and no installation
+// happens within the last given duration.
+func (ls *LinkState) Converged(quiet time.Duration) (time.Time, bool) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	var last time.Time
+	if len(ls.installs) > 0 {
+		last = ls.installs[len(ls.installs)-1].Time
+	}
+	return last, ls.synchronized() && ls.Network.Clock().Now().Sub(last) >= quiet
+}
+
+// synchronized reports whether all databases of servers having neighbors
+// contain the same LSAs, the lock must be held.
+func (ls *LinkState) synchronized() bool {
+	var first map[int]LSA
+	for port, db := range ls.databases {
+		if len(ls.neighbors[port]) == 0 {
+			continue
+		}
+		if first == nil {
+			first = db
+			continue
+		}
+		if len(db) != len(first) {
+			return false
+		}
+		for origin, lsa := range db {
+			if first[origin].Sequence != lsa.Sequence {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Vector computes shortest paths by the database of server on given port by
+// Dijkstra's algorithm.
+func (ls *LinkState) Vector(port int) Vector {
+	ls.mu.Lock()
+	db := make(map[int]LSA, len(ls.databases[port]))
+	for origin, lsa := range ls.databases[port] {
+		db[origin] = lsa
+	}
+	ls.mu.Unlock()
+
+	v := Vector{port: {0, port}}
+	done := make(map[int]bool)
+	for {
+		next, ok := 0, false
+		for dest, d := range v {
+			if !done[dest] && (!ok || d.Cost < v[next].Cost) {
+				next, ok = dest, true
+			}
+		}
+		if !ok {
+			return v
+		}
+		done[next] = true
+
+		for to, cost := range db[next].Links {
+			d := Distance{v[next].Cost + cost, v[next].NextHop}
+			if next == port {
+				d.NextHop = to
+			}
+			if old, ok := v[to]; !done[to] && (!ok || d.Cost < old.Cost) {
+				v[to] = d
+			}
+		}
+	}
+}
+
+// Install loads the forwarding tables of all servers by their own shortest
+// paths.
+func (ls *LinkState) Install() {
+	for _, port := range ls.Network.Ports() {
+		ls.Network.LoadTable(port, ls.Network.vectorTable(port, ls.Vector(port)))
+	}
+}
+
+// Inconsistency is a route of server differing from the shortest path.
+type Inconsistency struct {
+	Pair
+	// NextHop is the next hop of server and Expected is that of the shortest
+	// path, either is zero if unreachable.
+	NextHop, Expected Point
+	// Latency is of the route followed hop by hop through forwarding tables
+	// of servers and Optimal is of the shortest path, both on the graph.
+	Latency, Optimal time.Duration
+}
+
+// Inconsistent returns routes computed by servers which differ from the
+// shortest paths of the graph by Dijkstra's algorithm, e.g. the graph of
+// current Affinity of network. A route is inconsistent if it's reachable in
+// only one of them, the next hop differs, or the latency differs by more than
+// given tolerance, e.g. other servers on the route forward elsewhere.
+func (ls *LinkState) Inconsistent(g *Graph, tolerance time.Duration) []Inconsistency {
+	tables := make(map[Point]ForwardingTable)
+	for _, a := range g.Points() {
+		if port, ok := ls.Network.port(a); ok {
+			tables[a] = ls.Network.vectorTable(port, ls.Vector(port))
+		}
+	}
+
+	var found []Inconsistency
+	for _, a := range g.Points() {
+		if _, ok := tables[a]; !ok {
+			continue
+		}
+		paths := g.ShortestPaths(a)
+		for _, b := range g.Points() {
+			if a == b {
+				continue
+			}
+
+			x := Inconsistency{Pair: Pair{a, b}}
+			path, reachable := paths[b]
+			if reachable {
+				x.Expected = path[1]
+				p, _ := g.Profile(path)
+				x.Optimal = p.Latency
+			}
+			route, ok := follow(tables, a, b, len(g.Points()))
+			if ok {
+				x.NextHop = route[1]
+				var p Profile
+				p, ok = g.Profile(route)
+				x.Latency = p.Latency
+			}
+			delta := x.Latency - x.Optimal
+			if reachable != ok || x.NextHop != x.Expected || delta > tolerance || -delta > tolerance {
+				found = append(found, x)
+			}
+		}
+	}
+	return found
+}
+
+// follow returns the route from a to b through forwarding tables, and whether
+// it reaches b by at most given number of hops.
+func follow(tables map[Point]ForwardingTable, a, b Point, hops int) (Path, bool) {
+	route := Path{a}
+	for p := a; p != b; {
+		f, ok := tables[p].Lookup(b)
+		if !ok || len(route) > hops {
+			return nil, false
+		}
+		p = f.NextHop
+		route = append(route, p)
+	}
+	return route, true
+}
+
+// Divergent returns ordered pairs of points whose routes computed by the
+// server differ from the graph weighted by the costs of links advertised by
+// their origins instead, which only checks that the databases are flooded
+// to all servers regardless of the accuracy of measured round-trip times.
+// A pair is divergent if it's reachable in only one of them, or the next hop
+// differs.
+func (ls *LinkState) Divergent(g *Graph) []Pair {
+	var advertised Affinity
+	for _, a := range g.Points() {
+		from, ok := ls.Network.port(a)
+		ls.mu.Lock()
+		links := ls.databases[from][from].Links
+		ls.mu.Unlock()
+		for b, p := range g.edges[a] {
+			if to, ok2 := ls.Network.port(b); ok && ok2 {
+				if cost, ok := links[to]; ok {
+					p.Latency = cost
+				}
+			}
+			advertised = append(advertised, Link{A: a, B: b, Profile: p})
+		}
+	}
+	weighted := NewGraph(advertised)
+
+	var pairs []Pair
+	for _, a := range g.Points() {
+		port, ok := ls.Network.port(a)
+		if !ok {
+			continue
+		}
+		table := ls.Network.vectorTable(port, ls.Vector(port))
+		paths := weighted.ShortestPaths(a)
+		for _, b := range g.Points() {
+			path, reachable := paths[b]
+			f, ok := table.Lookup(b)
+			if a != b && (reachable != ok || ok && path[1] != f.NextHop) {
+				pairs = append(pairs, Pair{a, b})
+			}
+		}
+	}
+	return pairs
+}
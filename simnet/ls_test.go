@@ -0,0 +1,88 @@
+package simnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLinkState(t *testing.T) {
+	network, points, ports := chainNetwork(t)
+	defer network.Close()
+
+	// the timeout is long enough that a slow probe doesn't lose a link
+	ls := &LinkState{
+		Network:   network,
+		Period:    30 * time.Millisecond,
+		Timeout:   time.Second,
+		Tolerance: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ls.Run(ctx)
+
+	g := NewGraph(network.Affinity())
+	elapsed := converge(t, func() bool { return len(ls.Inconsistent(g, time.Millisecond)) == 0 }, 30*time.Second)
+	t.Logf("converged in %v by %v LSAs", elapsed, ls.Messages())
+	converge(t, func() bool { return len(ls.Divergent(g)) == 0 }, 30*time.Second)
+
+	d, ok := ls.Vector(ports[0])[ports[3]]
+	if p, _ := network.Point(d.NextHop); !ok || p != points[1] || d.Cost < 30*time.Millisecond {
+		t.Fatalf("expected %v reached through %v in >= 30ms, got %+v", points[3], points[1], d)
+	}
+
+	ls.Install()
+	hops, err := (&Dialer{Network: network, From: ports[0]}).TraceForward(ctx, ports[3], "/1k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) < 4 || hops[len(hops)-1].Port != ports[3] {
+		t.Errorf("expected forwarded through the chain, got %v", hops)
+	}
+
+	t.Run("Inconsistent", func(t *testing.T) {
+		// the graph where the link between the last two points is slower than
+		// the detour through a new one
+		detour := Point{City: cities["昆明市"], ISP: points[3].ISP}
+		if _, ok := network.port(detour); ok {
+			t.Skip("expected", detour, "not in network")
+		}
+		slow := network.Affinity()
+		for i, z := range slow {
+			if z.A == points[2] && z.B == points[3] || z.A == points[3] && z.B == points[2] {
+				slow[i].Latency = 50 * time.Millisecond
+			}
+		}
+		slow = append(slow,
+			Link{A: points[2], B: detour, Profile: Profile{Latency: 10 * time.Millisecond}},
+			Link{A: detour, B: points[3], Profile: Profile{Latency: 10 * time.Millisecond}})
+
+		var found Inconsistency
+		for _, x := range ls.Inconsistent(NewGraph(slow), time.Millisecond) {
+			if x.Pair == (Pair{points[0], points[3]}) {
+				found = x
+			}
+		}
+		if found.Pair != (Pair{points[0], points[3]}) {
+			t.Fatalf("expected %v inconsistent", Pair{points[0], points[3]})
+		}
+		if found.NextHop != points[1] || found.Expected != points[1] {
+			t.Errorf("expected next hops %v, got %+v", points[1], found)
+		}
+		if found.Latency != 70*time.Millisecond || found.Optimal != 40*time.Millisecond {
+			t.Errorf("expected latencies 70ms and 40ms, got %+v", found)
+		}
+	})
+
+	t.Run("Cut", func(t *testing.T) {
+		network.Cut(Selector{City: points[3].City.Name, ISP: points[3].ISP})
+		g := NewGraph(network.Affinity())
+		elapsed := converge(t, func() bool { return len(ls.Inconsistent(g, time.Millisecond)) == 0 }, 30*time.Second)
+		t.Logf("reconverged in %v", elapsed)
+		converge(t, func() bool { return len(ls.Divergent(g)) == 0 }, 30*time.Second)
+
+		if _, ok := ls.Vector(ports[0])[ports[3]]; ok {
+			t.Errorf("expected %v unreachable", points[3])
+		}
+	})
+}
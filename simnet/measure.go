@@ -0,0 +1,105 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// Sample is the result of downloading a file from b to a.
+type Sample struct {
+	A Point
+	B Point
+	// Size is the requested size in bytes.
+	Size int
+	// Connect is the elapsed time until the connection is established.
+	Connect time.Duration
+	// FirstByte is the elapsed time until the first byte of response arrives.
+	FirstByte time.Duration
+	// Total is the elapsed time until the whole response arrives.
+	Total time.Duration
+	// Bytes is the number of received bytes.
+	Bytes int64
+}
+
+// Throughput returns the downloading speed in kbit/s.
+func (s Sample) Throughput() float64 {
+	d := s.Total - s.FirstByte
+	if d <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) * 8 / 1000 / d.Seconds()
+}
+
+// Measurer measures downloading a file of size bytes from b to a, an error
+// is returned if b is unreachable from a.
+type Measurer interface {
+	Measure(ctx context.Context, a, b Point, size int) (Sample, error)
+}
+
+// Measure implements the Measurer interface by requesting from a server of b
+// on behalf of a server of a, the size is rounded up to kilobytes.
+func (n *Network) Measure(ctx context.Context, a, b Point, size int) (Sample, error) {
+	s := Sample{A: a, B: b, Size: size}
+	from, ok := n.port(a)
+	if !ok {
+		return s, fmt.Errorf("no server of %v", a)
+	}
+	to, ok := n.port(b)
+	if !ok {
+		return s, fmt.Errorf("no server of %v", b)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/%dk", to, (size+1023)/1024), nil)
+	if err != nil {
+		return s, err
+	}
+	req.Header.Set(FromHeader, fmt.Sprint(from))
+
+	clock := n.Clock()
+	since := clock.Now()
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			s.Connect = clock.Now().Sub(since)
+		},
+		GotFirstResponseByte: func() {
+			s.FirstByte = clock.Now().Sub(since)
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+	res, err := measureClient.Do(req)
+	if err != nil {
+		return s, err
+	}
+	defer res.Body.Close()
+
+	s.Bytes, err = io.Copy(ioutil.Discard, res.Body)
+	s.Total = clock.Now().Sub(since)
+	if err != nil {
+		return s, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return s, fmt.Errorf("unexpected status %v", res.Status)
+	}
+	return s, nil
+}
+
+// port returns the port of the first server of given point.
+func (n *Network) port(p Point) (int, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	port, ok := n.ports[p]
+	return port, ok
+}
+
+// measureClient uses a new connection for each request, so the connecting
+// time is always measured.
+var measureClient = &http.Client{
+	Transport: &http.Transport{DisableKeepAlives: true},
+}
@@ -0,0 +1,36 @@
+package simnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMeasure(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	points := network.Points()
+	a, b := points[0], points[1]
+	network.SetProfile(a, b, Profile{Latency: 100 * time.Millisecond, Bandwidth: 800})
+	engine := NewEngine(network.Affinity(), 1)
+
+	for _, m := range []Measurer{network, engine} {
+		s, err := m.Measure(context.Background(), a, b, 50*1024)
+		if err != nil {
+			t.Fatalf("%T: %v", m, err)
+		}
+		if s.Bytes != 50*1024 {
+			t.Errorf("%T: expected 50k bytes, got %v", m, s.Bytes)
+		}
+		if s.FirstByte < 100*time.Millisecond {
+			t.Errorf("%T: expected first byte after 100ms, got %v", m, s.FirstByte)
+		}
+		if s.Total < 600*time.Millisecond || s.Total > 900*time.Millisecond {
+			t.Errorf("%T: expected total about 600ms, got %v", m, s.Total)
+		}
+	}
+}
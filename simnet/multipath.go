@@ -0,0 +1,223 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Balance is the mode of splitting traffic across paths.
+type Balance int
+
+const (
+	// RoundRobin sends each request through the next path in turn.
+	RoundRobin Balance = iota
+	// FlowHash sends requests of the same flow through the same path.
+	FlowHash
+	// Weighted sends requests through paths in proportion to the weights,
+	// e.g. measured bandwidths, by smooth weighted round robin.
+	Weighted
+)
+
+// MultipathHeader is the HTTP header carrying a flow key, by which a request
+// forwarded by tables is hashed to the next hop or one of alternates, so that
+// flows are split across paths hop by hop.
+const MultipathHeader = "X-Simnet-Multipath"
+
+// Multipath sends requests of a dialer to the same destination across
+// several routes.
+type Multipath struct {
+	Dialer *Dialer
+	// Routes are the routes to the destination, each consists of ports of
+	// relays and the destination.
+	Routes  [][]int
+	Balance Balance
+	// Weights are the weights of routes for Weighted, which are equal if
+	// missing.
+	Weights []float64
+	// Concurrency is the number of chunks in flight of Stripe, the number of
+	// routes if non-positive.
+	Concurrency int
+
+	mu      sync.Mutex
+	next    int
+	current []float64
+}
+
+// Pick returns the index of route for a request of given flow, or -1 if no
+// routes.
+func (m *Multipath) Pick(flow string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.Routes) == 0 {
+		return -1
+	}
+	switch m.Balance {
+	case FlowHash:
+		return hashFlow(flow, len(m.Routes))
+	case Weighted:
+		if len(m.current) != len(m.Routes) {
+			m.current = make([]float64, len(m.Routes))
+		}
+		best, total := 0, 0.0
+		for i := range m.Routes {
+			w := 1.0
+			if i < len(m.Weights) {
+				w = m.Weights[i]
+			}
+			m.current[i] += w
+			total += w
+			if m.current[i] > m.current[best] {
+				best = i
+			}
+		}
+		m.current[best] -= total
+		return best
+	default:
+		i := m.next % len(m.Routes)
+		m.next++
+		return i
+	}
+}
+
+func (m *Multipath) concurrency() int {
+	if m.Concurrency <= 0 {
+		return len(m.Routes)
+	}
+	return m.Concurrency
+}
+
+func hashFlow(flow string, n int) int {
+	h := fnv.New32a()
+	io.WriteString(h, flow)
+	return int(h.Sum32() % uint32(n))
+}
+
+// Get requests the path through the route picked for the flow.
+func (m *Multipath) Get(ctx context.Context, flow, path string) (*http.Response, error) {
+	r := m.Pick(flow)
+	if r < 0 {
+		return nil, fmt.Errorf("no routes")
+	}
+	return m.Dialer.Get(ctx, m.Routes[r], path)
+}
+
+// MeasureWeights sets the weights by throughputs of downloading given size
+// in kbytes through each route, an unreachable route is of zero weight.
+func (m *Multipath) MeasureWeights(ctx context.Context, size int) error {
+	clock := m.Dialer.Network.Clock()
+	weights := make([]float64, len(m.Routes))
+	for i, route := range m.Routes {
+		since := clock.Now()
+		n, err := m.download(ctx, route, size)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if elapsed := clock.Now().Sub(since); err == nil && elapsed > 0 {
+			weights[i] = float64(n) * 8 / 1000 / elapsed.Seconds()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Weights, m.current = weights, nil
+	return nil
+}
+
+func (m *Multipath) download(ctx context.Context, route []int, size int) (int64, error) {
+	res, err := m.Dialer.Get(ctx, route, fmt.Sprintf("/%dk", size))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	return io.Copy(ioutil.Discard, res.Body)
+}
+
+// Transfer is the result of a striped download.
+type Transfer struct {
+	// Bytes is the number of bytes downloaded through each route.
+	Bytes   []int64
+	Elapsed time.Duration
+	// Failures is the number of failed chunks.
+	Failures int
+}
+
+// Throughput returns the aggregate throughput in kbit/s.
+func (t Transfer) Throughput() float64 {
+	var sum int64
+	for _, n := range t.Bytes {
+		sum += n
+	}
+	if t.Elapsed <= 0 {
+		return 0
+	}
+	return float64(sum) * 8 / 1000 / t.Elapsed.Seconds()
+}
+
+// Stripe downloads given size in kbytes by chunks concurrently, each chunk is
+// a flow sent through the route picked by the balance, and at most
+// Concurrency chunks are in flight.
+func (m *Multipath) Stripe(ctx context.Context, size, chunk int) (Transfer, error) {
+	if len(m.Routes) == 0 {
+		return Transfer{}, fmt.Errorf("no routes")
+	}
+	if chunk < 1 {
+		chunk = size
+	}
+
+	t := Transfer{Bytes: make([]int64, len(m.Routes))}
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		chunks = make(chan int)
+	)
+	clock := m.Dialer.Network.Clock()
+	since := clock.Now()
+	for i := 0; i < m.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range chunks {
+				n := chunk
+				if rest := size - i*chunk; rest < n {
+					n = rest
+				}
+				r := m.Pick(fmt.Sprint(i))
+				if r < 0 {
+					mu.Lock()
+					t.Failures++
+					mu.Unlock()
+					continue
+				}
+				got, err := m.download(ctx, m.Routes[r], n)
+
+				mu.Lock()
+				t.Bytes[r] += got
+				if err != nil {
+					t.Failures++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i*chunk < size; i++ {
+		select {
+		case chunks <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	t.Elapsed = clock.Now().Sub(since)
+	return t, ctx.Err()
+}
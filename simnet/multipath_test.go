@@ -0,0 +1,127 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMultipathPick(t *testing.T) {
+	m := &Multipath{Routes: make([][]int, 3)}
+	for i, want := range []int{0, 1, 2, 0} {
+		if r := m.Pick(""); r != want {
+			t.Errorf("expected round robin %v at %v, got %v", want, i, r)
+		}
+	}
+
+	m = &Multipath{Routes: make([][]int, 3), Balance: FlowHash}
+	if m.Pick("a") != m.Pick("a") {
+		t.Errorf("expected the same route of a flow")
+	}
+
+	m = &Multipath{Routes: make([][]int, 2), Balance: Weighted, Weights: []float64{3, 1}}
+	counts := make([]int, 2)
+	for i := 0; i < 8; i++ {
+		counts[m.Pick("")]++
+	}
+	if counts[0] != 6 || counts[1] != 2 {
+		t.Errorf("expected routes picked 6 and 2 times, got %v", counts)
+	}
+
+	for _, balance := range []Balance{RoundRobin, FlowHash, Weighted} {
+		m = &Multipath{Balance: balance}
+		if r := m.Pick("a"); r != -1 {
+			t.Errorf("expected no route picked by %v, got %v", balance, r)
+		}
+	}
+}
+
+// diamondNetwork creates a network where the first point reaches the fourth
+// through either the second or the third by links of given profile, and other
+// links are unreachable.
+func diamondNetwork(t *testing.T, p Profile) (*Network, []Point, []int) {
+	network, err := NewNetwork(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points := network.Points()
+	if len(points) < 4 {
+		network.Close()
+		t.Skip("expected at least 4 distinct points, got", len(points))
+	}
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				network.SetProfile(a, b, Profile{PacketLoss: 100})
+			}
+		}
+	}
+	points = points[:4]
+	var ports []int
+	for _, p := range points {
+		port, _ := network.port(p)
+		ports = append(ports, port)
+	}
+	for _, via := range points[1:3] {
+		network.SetProfile(points[0], via, p)
+		network.SetProfile(via, points[3], p)
+	}
+	return network, points, ports
+}
+
+func TestStripe(t *testing.T) {
+	network, _, ports := diamondNetwork(t, Profile{Bandwidth: 4000})
+	defer network.Close()
+
+	d := &Dialer{Network: network, From: ports[0]}
+	// the same number of chunks in flight, so that only paths differ
+	single := &Multipath{Dialer: d, Routes: [][]int{{ports[1], ports[3]}}, Concurrency: 2}
+	x, err := single.Stripe(context.Background(), 128, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Multipath{Dialer: d, Routes: [][]int{{ports[1], ports[3]}, {ports[2], ports[3]}}, Balance: Weighted}
+	if err := m.MeasureWeights(context.Background(), 16); err != nil {
+		t.Fatal(err)
+	}
+	if m.Weights[0] <= 0 || m.Weights[1] <= 0 {
+		t.Fatalf("expected positive weights, got %v", m.Weights)
+	}
+	y, err := m.Stripe(context.Background(), 128, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("single path %.0f kbit/s, striping %.0f kbit/s over %v", x.Throughput(), y.Throughput(), y.Bytes)
+	if y.Failures > 0 || y.Bytes[0]+y.Bytes[1] != 128*1024 {
+		t.Errorf("expected 128k downloaded, got %v with %v failures", y.Bytes, y.Failures)
+	}
+	if y.Throughput() < 1.3*x.Throughput() {
+		t.Errorf("expected striping faster than single path")
+	}
+}
+
+func TestMultipathForward(t *testing.T) {
+	network, points, ports := diamondNetwork(t, Profile{Latency: 5 * time.Millisecond})
+	defer network.Close()
+
+	network.LoadTables(BuildTables(NewGraph(network.Affinity()), ShortestPath{}, 1))
+	seen := make(map[int]bool)
+	for i := 0; i < 16; i++ {
+		d := &Dialer{Network: network, From: ports[0], Flow: fmt.Sprint(i)}
+		hops, err := d.TraceForward(context.Background(), ports[3], "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hops) < 3 || hops[len(hops)-1].Point != points[3] {
+			t.Fatalf("expected forwarded to %v, got %v", points[3], hops)
+		}
+		seen[hops[len(hops)-2].Port] = true
+	}
+	if !seen[ports[1]] || !seen[ports[2]] {
+		t.Errorf("expected flows split across %v and %v, got %v", ports[1], ports[2], seen)
+	}
+}
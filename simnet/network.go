@@ -0,0 +1,421 @@
+package simnet
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FromHeader is the HTTP header carrying the port of the server where a
+// request comes from.
+const FromHeader = "X-Simnet-From"
+
+// Selector selects points, a zero field matches any.
+type Selector struct {
+	City     string
+	Province string
+	District string
+	ISP      ISP
+}
+
+// Match reports whether the point is selected.
+func (s Selector) Match(p Point) bool {
+	return (s.City == "" || s.City == p.City.Name) &&
+		(s.Province == "" || s.Province == p.City.Province) &&
+		(s.District == "" || s.District == p.City.District) &&
+		(s.ISP == 0 || s.ISP == p.ISP)
+}
+
+// Fault degrades links between points selected by A and points selected by B
+// in both directions, where a link is affected only if A selects exactly one
+// of its ends and B selects the other.
+//
+// The packet loss of an affected link is raised to the PacketLoss, the
+// Latency is added and the bandwidth is limited to the Bandwidth if non-zero.
+type Fault struct {
+	A, B Selector
+	Profile
+}
+
+// CutOff returns a fault making selected points unreachable from or to the
+// rest of network.
+func CutOff(s Selector) Fault {
+	return Fault{A: s, Profile: Profile{PacketLoss: 100}}
+}
+
+// Degrade returns a fault degrading links from or to selected points.
+func Degrade(s Selector, p Profile) Fault {
+	return Fault{A: s, Profile: p}
+}
+
+// CutPeering returns a fault making ISP a and b unreachable from each other.
+func CutPeering(a, b ISP) Fault {
+	return Fault{A: Selector{ISP: a}, B: Selector{ISP: b}, Profile: Profile{PacketLoss: 100}}
+}
+
+// Apply returns the profile of link from a to b with the fault.
+func (f Fault) Apply(a, b Point, p Profile) Profile {
+	if !(f.A.Match(a) && !f.A.Match(b) && f.B.Match(b) ||
+		f.A.Match(b) && !f.A.Match(a) && f.B.Match(a)) {
+		return p
+	}
+
+	if f.PacketLoss > p.PacketLoss {
+		p.PacketLoss = f.PacketLoss
+	}
+	p.Latency += f.Latency
+	if f.Bandwidth > 0 && (p.Bandwidth == 0 || f.Bandwidth < p.Bandwidth) {
+		p.Bandwidth = f.Bandwidth
+	}
+	return p
+}
+
+// Pair is an ordered pair of points.
+type Pair struct {
+	A, B Point
+}
+
+// Network is a fleet of HTTP servers whose traffic is shaped by link profiles
+// between the points of servers.
+type Network struct {
+	mu      sync.RWMutex
+	servers map[int]Point
+	coords  map[int]*Vivaldi
+	ports   map[Point]int
+	links   map[Pair]Profile
+	flows   map[Pair]int
+	tables  map[int]ForwardingTable
+	routes  map[string]func(port int, w http.ResponseWriter, r *http.Request)
+	faults  map[int]Fault
+	nextID  int
+	varies  []Variation
+	clock   Clock
+	cancel  context.CancelFunc
+}
+
+// NewNetwork creates n servers sharing a network, each server is associated
+// with a point by NewPoint, and the links are initialized by NewAffinity.
+// All servers are closed once ctx is done or Close is called.
+func NewNetwork(ctx context.Context, n int) (*Network, error) {
+	network := &Network{
+		servers: make(map[int]Point),
+		coords:  make(map[int]*Vivaldi),
+		ports:   make(map[Point]int),
+		links:   make(map[Pair]Profile),
+		flows:   make(map[Pair]int),
+		tables:  make(map[int]ForwardingTable),
+		routes:  make(map[string]func(int, http.ResponseWriter, *http.Request)),
+		faults:  make(map[int]Fault),
+		clock:   RealClock,
+	}
+
+	var localCtx context.Context
+	localCtx, network.cancel = context.WithCancel(ctx)
+	var points []Point
+	for i := 0; i < n; i++ {
+		port, err := listenHTTP(localCtx, network.handler)
+		if err != nil {
+			network.Close()
+			return nil, err
+		}
+
+		p := NewPoint(port)
+		network.servers[port] = p
+		network.coords[port] = NewVivaldi(int64(port))
+		if _, ok := network.ports[p]; !ok {
+			network.ports[p] = port
+			points = append(points, p)
+		}
+	}
+
+	for _, z := range NewAffinity(points) {
+		network.links[Pair{z.A, z.B}] = z.Profile
+	}
+	return network, nil
+}
+
+// Close shuts down all servers.
+func (n *Network) Close() {
+	n.cancel()
+}
+
+// SetClock sets the clock used by the network, which is RealClock by default.
+func (n *Network) SetClock(c Clock) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.clock = c
+}
+
+// Clock returns the clock used by the network.
+func (n *Network) Clock() Clock {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.clock
+}
+
+// Ports returns ports of all servers in ascending order.
+func (n *Network) Ports() []int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	ports := make([]int, 0, len(n.servers))
+	for port := range n.servers {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// Point returns the point of server on given port.
+func (n *Network) Point(port int) (Point, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	p, ok := n.servers[port]
+	return p, ok
+}
+
+// Profile returns the current profile of the link from a to b, including
+// variations and faults.
+func (n *Network) Profile(a, b Point) Profile {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.profile(a, b)
+}
+
+func (n *Network) profile(a, b Point) Profile {
+	p := n.links[Pair{a, b}]
+	if len(n.varies) > 0 {
+		now := n.clock.Now()
+		for _, v := range n.varies {
+			p = v.Vary(now, a, b, p)
+		}
+	}
+	for _, f := range n.faults {
+		p = f.Apply(a, b, p)
+	}
+	return p
+}
+
+// SetProfile updates the profile of the link from a to b.
+func (n *Network) SetProfile(a, b Point, p Profile) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.links[Pair{a, b}] = p
+}
+
+// Inject adds a fault to the network, it returns an ID to clear the fault.
+func (n *Network) Inject(f Fault) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.nextID++
+	n.faults[n.nextID] = f
+	return n.nextID
+}
+
+// Clear removes the fault of given ID.
+func (n *Network) Clear(id int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.faults, id)
+}
+
+// Vary adds variations to all links of the network.
+func (n *Network) Vary(vs ...Variation) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.varies = append(n.varies, vs...)
+}
+
+// Cut makes selected points unreachable from or to the rest of network.
+func (n *Network) Cut(s Selector) {
+	n.Inject(CutOff(s))
+}
+
+// Restore reverts all previous cuts by the same selector.
+func (n *Network) Restore(s Selector) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f := CutOff(s)
+	for id, v := range n.faults {
+		if v == f {
+			delete(n.faults, id)
+		}
+	}
+}
+
+// Points returns distinct points of all servers ordered by city and ISP.
+func (n *Network) Points() []Point {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.points()
+}
+
+func (n *Network) points() []Point {
+	points := make([]Point, 0, len(n.ports))
+	for p := range n.ports {
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return lessPoint(points[i], points[j])
+	})
+	return points
+}
+
+// Affinity returns the current affinity between points of all servers.
+func (n *Network) Affinity() Affinity {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	points := n.points()
+	r := make(Affinity, 0, len(points)*len(points))
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				r = append(r, Link{A: a, B: b, Profile: n.profile(a, b)})
+			}
+		}
+	}
+	return r
+}
+
+// Handle registers the handler of path for all servers, which is called with
+// the port of server after the link from the request source is applied. A nil
+// handler unregisters the path.
+func (n *Network) Handle(path string, h func(port int, w http.ResponseWriter, r *http.Request)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if h == nil {
+		delete(n.routes, path)
+	} else {
+		n.routes[path] = h
+	}
+}
+
+// handler returns the handler of server on given port, which applies the
+// profile of link from the request source before serving.
+//
+// Besides requests supported by Handler, it supports:
+//
+//	GET /coordinate - returns the network coordinate of the server
+//	GET /table - returns the forwarding table of the server
+//	PUT /table - loads the forwarding table of the server
+//
+// and paths registered by Handle.
+//
+// A request with the RouteHeader is relayed to the next hop, otherwise a
+// request with the DestinationHeader of another server is forwarded by the
+// forwarding table.
+func (n *Network) handler(port int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hop := Hop{Port: port, Arrived: n.Clock().Now()}
+		if from, err := strconv.Atoi(r.Header.Get(FromHeader)); err == nil {
+			var done func()
+			w, done = n.shape(from, port, w)
+			defer done()
+		}
+		hop.Point, _ = n.Point(port)
+		hop.Forwarded = n.Clock().Now()
+
+		if r.Header.Get(RouteHeader) != "" {
+			n.relay(hop, w, r)
+			return
+		}
+		if dest := r.Header.Get(DestinationHeader); dest != "" && dest != strconv.Itoa(port) {
+			n.forwardByTable(hop, w, r)
+			return
+		}
+		if r.Header.Get(TraceHeader) != "" {
+			addHop(w.Header(), hop)
+		}
+
+		switch r.URL.Path {
+		case "/coordinate":
+			writeJSON(w, n.coordinate(port).Coordinate())
+		case "/table":
+			n.serveTable(port, w, r)
+		default:
+			n.mu.RLock()
+			h, ok := n.routes[r.URL.Path]
+			n.mu.RUnlock()
+			if ok {
+				h(port, w, r)
+			} else {
+				Handler(w, r)
+			}
+		}
+	}
+}
+
+// shape applies the profile of link between servers, it aborts the request
+// if dropped. The bandwidth of link is equally shared by responses in flight
+// through it, the returned function must be called once the response is done.
+func (n *Network) shape(from, to int, w http.ResponseWriter) (http.ResponseWriter, func()) {
+	n.mu.RLock()
+	a, okA := n.servers[from]
+	b, okB := n.servers[to]
+	var p Profile
+	if okA && okB {
+		p = n.profile(a, b)
+	}
+	clock := n.clock
+	n.mu.RUnlock()
+
+	if p.PacketLoss > 0 && rand.Intn(100) < p.PacketLoss {
+		// aborts the connection as if packets were dropped
+		panic(http.ErrAbortHandler)
+	}
+	clock.Sleep(p.Latency)
+	if p.Bandwidth <= 0 {
+		return w, func() {}
+	}
+
+	link := Pair{a, b}
+	n.mu.Lock()
+	n.flows[link]++
+	n.mu.Unlock()
+	w = &throttledWriter{ResponseWriter: w, bandwidth: p.Bandwidth, clock: clock, network: n, link: link}
+	return w, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if n.flows[link]--; n.flows[link] <= 0 {
+			delete(n.flows, link)
+		}
+	}
+}
+
+// throttledWriter limits writing speed to the share of bandwidth in kbit/s
+// among flows on the link.
+type throttledWriter struct {
+	http.ResponseWriter
+	bandwidth int
+	clock     Clock
+	network   *Network
+	link      Pair
+}
+
+func (w *throttledWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.network.mu.RLock()
+	k := w.network.flows[w.link]
+	w.network.mu.RUnlock()
+	if k < 1 {
+		k = 1
+	}
+	w.clock.Sleep(time.Duration(n) * 8 * time.Second * time.Duration(k) / time.Duration(w.bandwidth*1000))
+	return n, err
+}
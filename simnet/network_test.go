@@ -0,0 +1,137 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func getFrom(from, to int, path string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%v%v", to, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(FromHeader, fmt.Sprint(from))
+	return http.DefaultClient.Do(req)
+}
+
+func TestNetwork(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	ports := network.Ports()
+	if len(ports) != 10 {
+		t.Fatalf("expected 10 servers, got %v", len(ports))
+	}
+
+	// the ends differ in province or ISP, so that cutting one of them by a
+	// selector of both never matches the other
+	var from, to int
+	for _, a := range ports {
+		for _, b := range ports {
+			pa, _ := network.Point(a)
+			pb, _ := network.Point(b)
+			if pa.City.Province != pb.City.Province || pa.ISP != pb.ISP {
+				from, to = a, b
+			}
+		}
+	}
+	if from == 0 {
+		t.Skip("all servers are within the same province and ISP")
+	}
+	a, _ := network.Point(from)
+	b, _ := network.Point(to)
+
+	t.Run("Latency", func(t *testing.T) {
+		network.SetProfile(a, b, Profile{Latency: 200 * time.Millisecond})
+		since := time.Now()
+		res, err := getFrom(from, to, "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if elapsed := time.Since(since); elapsed < 200*time.Millisecond {
+			t.Errorf("expected elapse >= 200ms, got %v", elapsed)
+		}
+	})
+
+	t.Run("Bandwidth", func(t *testing.T) {
+		network.SetProfile(a, b, Profile{Bandwidth: 64})
+		since := time.Now()
+		res, err := getFrom(from, to, "/8k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		if elapsed := time.Since(since); elapsed < time.Second {
+			t.Errorf("expected elapse >= 1s, got %v", elapsed)
+		}
+	})
+
+	t.Run("Shared bandwidth", func(t *testing.T) {
+		network.SetProfile(a, b, Profile{Bandwidth: 256})
+		since := time.Now()
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				res, err := getFrom(from, to, "/32k")
+				if err == nil {
+					io.Copy(ioutil.Discard, res.Body)
+					res.Body.Close()
+				}
+				errs <- err
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if elapsed := time.Since(since); elapsed < 1500*time.Millisecond {
+			t.Errorf("expected elapse >= 1.5s, got %v", elapsed)
+		}
+	})
+
+	t.Run("Scaled clock", func(t *testing.T) {
+		network.SetClock(NewScaledClock(100))
+		defer network.SetClock(RealClock)
+
+		network.SetProfile(a, b, Profile{Latency: 10 * time.Second})
+		since := time.Now()
+		res, err := getFrom(from, to, "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if elapsed := time.Since(since); elapsed < 100*time.Millisecond || elapsed > time.Second {
+			t.Errorf("expected elapse about 100ms, got %v", elapsed)
+		}
+	})
+
+	t.Run("Cut", func(t *testing.T) {
+		network.SetProfile(a, b, Profile{})
+		s := Selector{Province: a.City.Province, ISP: a.ISP}
+		network.Cut(s)
+		if _, err := getFrom(from, to, "/1k"); err == nil {
+			t.Error("expected an error, got nil")
+		}
+		if p := network.Profile(b, a); p.PacketLoss != 100 {
+			t.Errorf("expected 100%% packet loss from %v to %v, got %v", b, a, p.PacketLoss)
+		}
+
+		network.Restore(s)
+		res, err := getFrom(from, to, "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	})
+}
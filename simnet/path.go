@@ -0,0 +1,154 @@
+package simnet
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// Path is a sequence of points.
+type Path []Point
+
+// Compose returns the profile of traversing links in order, where the
+// delivery ratios are multiplied, the latencies are added and the bandwidth is
+// the minimum.
+func Compose(ps ...Profile) Profile {
+	var r Profile
+	delivery := 1.0
+	for _, p := range ps {
+		delivery *= 1 - float64(p.PacketLoss)/100
+		r.Latency += p.Latency
+		if p.Bandwidth > 0 && (r.Bandwidth == 0 || p.Bandwidth < r.Bandwidth) {
+			r.Bandwidth = p.Bandwidth
+		}
+	}
+	r.PacketLoss = 100 - int(delivery*100+0.5)
+	return r
+}
+
+// Graph is a directed graph of points, where edges are reachable links.
+type Graph struct {
+	points []Point
+	edges  map[Point]map[Point]Profile
+}
+
+// NewGraph creates a graph of links with less than 100% packet loss.
+func NewGraph(graph Affinity) *Graph {
+	g := &Graph{edges: make(map[Point]map[Point]Profile)}
+	for _, z := range graph {
+		g.add(z.A)
+		g.add(z.B)
+		if z.PacketLoss < 100 {
+			g.edges[z.A][z.B] = z.Profile
+		}
+	}
+	return g
+}
+
+func (g *Graph) add(p Point) {
+	if _, ok := g.edges[p]; !ok {
+		g.edges[p] = make(map[Point]Profile)
+		g.points = append(g.points, p)
+	}
+}
+
+// Points returns all points in order of appearance.
+func (g *Graph) Points() []Point {
+	return g.points
+}
+
+// Edge returns the profile of link from a to b, and whether it's reachable.
+func (g *Graph) Edge(a, b Point) (Profile, bool) {
+	p, ok := g.edges[a][b]
+	return p, ok
+}
+
+// neighbors returns points reachable by direct links from given point in
+// order.
+func (g *Graph) neighbors(a Point) []Point {
+	ps := make([]Point, 0, len(g.edges[a]))
+	for b := range g.edges[a] {
+		ps = append(ps, b)
+	}
+	sort.Slice(ps, func(i, j int) bool { return lessPoint(ps[i], ps[j]) })
+	return ps
+}
+
+// Profile returns the composed profile along the path, and whether all links
+// are reachable.
+func (g *Graph) Profile(path Path) (Profile, bool) {
+	ps := make([]Profile, 0, len(path))
+	for i := 1; i < len(path); i++ {
+		p, ok := g.Edge(path[i-1], path[i])
+		if !ok {
+			return Profile{PacketLoss: 100}, false
+		}
+		ps = append(ps, p)
+	}
+	return Compose(ps...), true
+}
+
+// ShortestPaths returns the paths of the least latency from given point to
+// all reachable points, including the point itself.
+func (g *Graph) ShortestPaths(from Point) map[Point]Path {
+	return g.shortestPaths(from, nil)
+}
+
+// shortestPaths is like ShortestPaths but skips points and links excluded by
+// the skip if non-nil.
+func (g *Graph) shortestPaths(from Point, skip func(a, b Point) bool) map[Point]Path {
+	dist := map[Point]time.Duration{from: 0}
+	prev := make(map[Point]Point)
+	done := make(map[Point]bool)
+	q := &pointQueue{{from, 0}}
+	for q.Len() > 0 {
+		a := heap.Pop(q).(pointItem).point
+		if done[a] {
+			continue
+		}
+		done[a] = true
+
+		for b, p := range g.edges[a] {
+			if done[b] || skip != nil && skip(a, b) {
+				continue
+			}
+			if d, ok := dist[b]; !ok || dist[a]+p.Latency < d {
+				dist[b] = dist[a] + p.Latency
+				prev[b] = a
+				heap.Push(q, pointItem{b, dist[b]})
+			}
+		}
+	}
+
+	paths := make(map[Point]Path, len(dist))
+	for p := range dist {
+		var path Path
+		for v := p; v != from; v = prev[v] {
+			path = append(path, v)
+		}
+		path = append(path, from)
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+		paths[p] = path
+	}
+	return paths
+}
+
+type pointItem struct {
+	point    Point
+	distance time.Duration
+}
+
+type pointQueue []pointItem
+
+func (q pointQueue) Len() int            { return len(q) }
+func (q pointQueue) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q pointQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pointQueue) Push(x interface{}) { *q = append(*q, x.(pointItem)) }
+func (q *pointQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
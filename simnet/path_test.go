@@ -0,0 +1,44 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompose(t *testing.T) {
+	p := Compose(
+		Profile{PacketLoss: 10, Latency: 10 * time.Millisecond, Bandwidth: 1000},
+		Profile{PacketLoss: 20, Latency: 20 * time.Millisecond},
+		Profile{Latency: 5 * time.Millisecond, Bandwidth: 500},
+	)
+	if p != (Profile{PacketLoss: 28, Latency: 35 * time.Millisecond, Bandwidth: 500}) {
+		t.Errorf("unexpected profile %+v", p)
+	}
+}
+
+func TestShortestPaths(t *testing.T) {
+	a := Point{City: cities["北京市"], ISP: 1}
+	b := Point{City: cities["上海市"], ISP: 1}
+	c := Point{City: cities["广州市"], ISP: 1}
+	d := Point{City: cities["成都市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: b, B: c, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: a, B: c, Profile: Profile{Latency: 30 * time.Millisecond}},
+		{A: c, B: d, Profile: Profile{PacketLoss: 100}},
+	})
+
+	paths := g.ShortestPaths(a)
+	if path := paths[c]; len(path) != 3 || path[1] != b {
+		t.Errorf("expected path through %v, got %v", b, path)
+	}
+	if path := paths[a]; len(path) != 1 {
+		t.Errorf("expected path to itself, got %v", path)
+	}
+	if _, ok := paths[d]; ok {
+		t.Errorf("expected %v unreachable", d)
+	}
+	if p, ok := g.Profile(paths[c]); !ok || p.Latency != 20*time.Millisecond {
+		t.Errorf("expected latency 20ms, got %v", p.Latency)
+	}
+}
@@ -0,0 +1,185 @@
+package simnet
+
+import (
+	"math"
+	"time"
+)
+
+// Objective is the objective of relay placement.
+type Objective int
+
+const (
+	// MaxReachable maximizes the number of reachable pairs, then minimizes
+	// the mean latency of reachable pairs.
+	MaxReachable Objective = iota
+	// MinLatency minimizes the mean best-path latency over all pairs, where
+	// an unreachable pair counts as the unreachablePenalty.
+	MinLatency
+)
+
+// unreachablePenalty is the latency of an unreachable pair on MinLatency.
+const unreachablePenalty = 10 * maxLatency
+
+// Placement is a set of relays and the evaluation.
+type Placement struct {
+	Relays []Point
+	// Reachable is the number of reachable ordered pairs.
+	Reachable int
+	// Pairs is the number of all ordered pairs.
+	Pairs int
+	// Latency is the mean best-path latency of reachable pairs.
+	Latency time.Duration
+}
+
+// better reports whether p is better than o by the objective.
+func (p Placement) better(o Placement, objective Objective) bool {
+	switch objective {
+	case MinLatency:
+		return p.penalized() < o.penalized()
+	default:
+		return p.Reachable > o.Reachable || p.Reachable == o.Reachable && p.Latency < o.Latency
+	}
+}
+
+func (p Placement) penalized() float64 {
+	if p.Pairs == 0 {
+		return 0
+	}
+	unreachable := p.Pairs - p.Reachable
+	return (float64(p.Latency)*float64(p.Reachable) + float64(unreachablePenalty)*float64(unreachable)) / float64(p.Pairs)
+}
+
+// distances is a matrix of best-path latencies in seconds between points,
+// where only relays can be intermediate hops.
+type distances [][]float64
+
+func newDistances(g *Graph) distances {
+	points := g.Points()
+	index := make(map[Point]int, len(points))
+	for i, p := range points {
+		index[p] = i
+	}
+
+	d := make(distances, len(points))
+	for i, a := range points {
+		d[i] = make([]float64, len(points))
+		for j := range d[i] {
+			if i != j {
+				d[i][j] = math.Inf(1)
+			}
+		}
+		for b, p := range g.edges[a] {
+			d[i][index[b]] = math.Min(d[i][index[b]], p.Latency.Seconds())
+		}
+	}
+	return d
+}
+
+// alloc returns a matrix of the same size.
+func (d distances) alloc() distances {
+	r := make(distances, len(d))
+	for i := range d {
+		r[i] = make([]float64, len(d[i]))
+	}
+	return r
+}
+
+// relay stores into r the distances after allowing point k as intermediate
+// hops, which is a step of Floyd–Warshall algorithm. The r may be d itself,
+// since the row and column of k are unchanged by the step.
+func (d distances) relay(k int, r distances) {
+	for i := range d {
+		for j := range d[i] {
+			r[i][j] = math.Min(d[i][j], d[i][k]+d[k][j])
+		}
+	}
+}
+
+func (d distances) evaluate() Placement {
+	var p Placement
+	var sum float64
+	for i := range d {
+		for j := range d[i] {
+			if i == j {
+				continue
+			}
+			p.Pairs++
+			if !math.IsInf(d[i][j], 1) {
+				p.Reachable++
+				sum += d[i][j]
+			}
+		}
+	}
+	if p.Reachable > 0 {
+		p.Latency = time.Duration(sum / float64(p.Reachable) * float64(time.Second))
+	}
+	return p
+}
+
+// EvaluateRelays evaluates given relays on the graph, where pairs are
+// connected by direct links or paths through the relays.
+func EvaluateRelays(g *Graph, relays []Point) Placement {
+	index := make(map[Point]int)
+	for i, p := range g.Points() {
+		index[p] = i
+	}
+
+	d := newDistances(g)
+	for _, p := range relays {
+		if k, ok := index[p]; ok {
+			d.relay(k, d)
+		}
+	}
+	r := d.evaluate()
+	r.Relays = relays
+	return r
+}
+
+// PlaceRelays chooses at most k points of the graph as relays greedily, where
+// each step adds the point improving the objective most, until no point
+// improves it.
+func PlaceRelays(g *Graph, k int, objective Objective) Placement {
+	points := g.Points()
+	d := newDistances(g)
+	best := d.evaluate()
+	chosen := make(map[int]bool)
+
+	// candidates are evaluated in the scratch, and the best one so far is
+	// kept in nextD by swapping, so no matrix is allocated per candidate
+	scratch, nextD := d.alloc(), d.alloc()
+	for len(best.Relays) < k {
+		next, candidate := -1, best
+		for i := range points {
+			if chosen[i] {
+				continue
+			}
+
+			d.relay(i, scratch)
+			if p := scratch.evaluate(); p.better(candidate, objective) {
+				next, candidate = i, p
+				scratch, nextD = nextD, scratch
+			}
+		}
+		if next < 0 {
+			break
+		}
+
+		chosen[next] = true
+		d, nextD = nextD, d
+		candidate.Relays = append(best.Relays, points[next])
+		best = candidate
+	}
+	return best
+}
+
+// TierRelays returns points of first-class cores and second-class influxes,
+// which are the relays by design.
+func TierRelays(points []Point) []Point {
+	var relays []Point
+	for _, p := range points {
+		if Tier(p.City.Name) <= 2 {
+			relays = append(relays, p)
+		}
+	}
+	return relays
+}
@@ -0,0 +1,93 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlaceRelays(t *testing.T) {
+	a := Point{City: cities["昆明市"], ISP: 1}
+	b := Point{City: cities["杭州市"], ISP: 1}
+	c := Point{City: cities["成都市"], ISP: 1}
+	d := Point{City: cities["上海市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: c, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: c, B: d, Profile: Profile{Latency: 20 * time.Millisecond}},
+		{A: d, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: b, B: a, Profile: Profile{PacketLoss: 100}},
+	})
+
+	if p := EvaluateRelays(g, nil); p.Reachable != 3 || p.Pairs != 12 {
+		t.Errorf("expected 3 of 12 pairs reachable without relays, got %+v", p)
+	}
+
+	p := PlaceRelays(g, 2, MaxReachable)
+	if len(p.Relays) != 2 || p.Reachable != 6 {
+		t.Fatalf("expected 6 pairs reachable by 2 relays, got %+v", p)
+	}
+	for _, v := range p.Relays {
+		if v != c && v != d {
+			t.Errorf("expected relays %v and %v, got %v", c, d, p.Relays)
+		}
+	}
+	if v := EvaluateRelays(g, p.Relays); v.Reachable != p.Reachable || v.Latency != p.Latency {
+		t.Errorf("expected evaluation %+v, got %+v", p, v)
+	}
+
+	if p := PlaceRelays(g, 5, MinLatency); len(p.Relays) != 2 {
+		t.Errorf("expected stopping at 2 relays, got %v", p.Relays)
+	}
+}
+
+func TestPlaceRelaysBeyondTier(t *testing.T) {
+	// the core reaches only one point, while a non-tier hub connects all
+	hub := Point{City: cities["苏州市"], ISP: 1}
+	core := Point{City: cities["北京市"], ISP: 1}
+	points := []Point{
+		{City: cities["杭州市"], ISP: 1},
+		{City: cities["南京市"], ISP: 1},
+		{City: cities["合肥市"], ISP: 1},
+	}
+	var graph Affinity
+	for _, p := range points {
+		graph = append(graph,
+			Link{A: p, B: hub, Profile: Profile{Latency: 10 * time.Millisecond}},
+			Link{A: hub, B: p, Profile: Profile{Latency: 10 * time.Millisecond}},
+		)
+	}
+	graph = append(graph,
+		Link{A: core, B: points[0], Profile: Profile{Latency: 10 * time.Millisecond}},
+		Link{A: points[0], B: core, Profile: Profile{Latency: 10 * time.Millisecond}},
+	)
+	g := NewGraph(graph)
+
+	tier := EvaluateRelays(g, TierRelays(g.Points()))
+	if len(tier.Relays) != 1 || tier.Reachable != 8 {
+		t.Fatalf("expected 8 pairs reachable by the core, got %+v", tier)
+	}
+	greedy := PlaceRelays(g, 1, MaxReachable)
+	if len(greedy.Relays) != 1 || greedy.Relays[0] != hub || greedy.Reachable != 14 {
+		t.Errorf("expected 14 pairs reachable by %v, got %+v", hub, greedy)
+	}
+}
+
+func TestTierRelays(t *testing.T) {
+	var points []Point
+	for _, name := range names {
+		if Tier(name) <= 3 {
+			for i := 0; i < 3; i++ {
+				points = append(points, Point{City: cities[name], ISP: isps[i]})
+			}
+		}
+	}
+	g := NewGraph(NewAffinity(points))
+
+	tier := EvaluateRelays(g, TierRelays(points))
+	greedy := PlaceRelays(g, len(tier.Relays), MaxReachable)
+	if greedy.Reachable < tier.Reachable {
+		t.Errorf("expected greedy placement reaching at least %v pairs, got %v", tier.Reachable, greedy.Reachable)
+	}
+	t.Logf("%v tier relays: %v/%v pairs in %v; %v greedy relays: %v/%v pairs in %v",
+		len(tier.Relays), tier.Reachable, tier.Pairs, tier.Latency,
+		len(greedy.Relays), greedy.Reachable, greedy.Pairs, greedy.Latency)
+}
@@ -0,0 +1,117 @@
+package simnet
+
+import (
+	"context"
+	"math/rand"
+)
+
+// Plan is a set of pairs to probe.
+type Plan struct {
+	// Probes are pairs whose measurements are used to estimate the rest.
+	Probes []Pair
+	// Holdout are pairs measured only to validate the estimation.
+	Holdout []Pair
+}
+
+// Planner plans a probe set instead of every ordered pair of points.
+//
+// Pairs within same province, and pairs between points and their upper
+// cities, i.e. the influx of the district and the cores, are always probed,
+// so any two points are connected through the tier hierarchy. Then a number
+// of random cross pairs are probed by the CrossRate.
+type Planner struct {
+	// CrossRate is the ratio of the rest pairs to probe.
+	CrossRate float64
+	// HoldoutRate is the ratio of the rest pairs to validate the estimation.
+	HoldoutRate float64
+	// Seed is used to sample the rest pairs.
+	Seed int64
+}
+
+// Plan returns the plan of given points.
+func (p *Planner) Plan(points []Point) Plan {
+	var plan Plan
+	r := rand.New(rand.NewSource(p.Seed))
+	for _, a := range points {
+		for _, b := range points {
+			if a == b {
+				continue
+			}
+
+			k := Pair{a, b}
+			switch {
+			case a.City.Province == b.City.Province,
+				isUplink(a.City, b.City), isUplink(b.City, a.City):
+				plan.Probes = append(plan.Probes, k)
+			default:
+				x := r.Float64()
+				if x < p.CrossRate {
+					plan.Probes = append(plan.Probes, k)
+				} else if x < p.CrossRate+p.HoldoutRate {
+					plan.Holdout = append(plan.Holdout, k)
+				}
+			}
+		}
+	}
+	return plan
+}
+
+// Run probes planned pairs, then estimates the affinity of all pairs of
+// points, and reports the estimation error on the holdout.
+func (plan Plan) Run(ctx context.Context, prober *Prober, points []Point) (Affinity, *DriftReport, error) {
+	m, err := prober.ProbePairs(ctx, append(append([]Pair(nil), plan.Probes...), plan.Holdout...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	probes := make(Matrix, len(plan.Probes))
+	for _, k := range plan.Probes {
+		probes[k] = m[k]
+	}
+	holdout := make(Matrix, len(plan.Holdout))
+	for _, k := range plan.Holdout {
+		holdout[k] = m[k]
+	}
+
+	estimated := Estimate(probes, points)
+	return estimated, NewDriftReport(estimated, InferAffinity(holdout)), nil
+}
+
+// Estimate returns the affinity of all pairs of points. The measured pairs
+// are inferred from m, and the rest are estimated by composing links along
+// the path of the least latency through measured pairs, or unreachable if no
+// such path.
+func Estimate(m Matrix, points []Point) Affinity {
+	g := NewGraph(InferAffinity(m))
+	r := make(Affinity, 0, len(points)*len(points))
+	for _, a := range points {
+		paths := g.ShortestPaths(a)
+		for _, b := range points {
+			if a == b {
+				continue
+			}
+
+			z := Link{A: a, B: b}
+			if _, ok := m[Pair{a, b}]; ok {
+				if p, ok := g.Edge(a, b); ok {
+					z.Profile = p
+				} else {
+					z.PacketLoss = 100
+				}
+			} else if path, ok := paths[b]; ok {
+				z.Profile, _ = g.Profile(path)
+			} else {
+				z.PacketLoss = 100
+			}
+			r = append(r, z)
+		}
+	}
+	return r
+}
+
+// isUplink reports whether b is an upper city of a in the tier hierarchy,
+// i.e. b is a core or the influx of the district of a.
+func isUplink(a, b City) bool {
+	tier := Tier(b.Name)
+	return tier == 1 || tier == 2 && a.District == b.District
+}
@@ -0,0 +1,99 @@
+package simnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPlanner(t *testing.T) {
+	var points []Point
+	for i, name := range names {
+		if i%3 == 0 || Tier(name) < 3 {
+			p := NewPointFromCity(name)
+			p.ISP = 1
+			points = append(points, p)
+		}
+	}
+
+	// makes reachable links lossless, so that the estimation is checked
+	// without random drops
+	graph := NewAffinity(points)
+	for i := range graph {
+		if graph[i].PacketLoss < 100 {
+			graph[i].PacketLoss = 0
+		}
+	}
+
+	planner := &Planner{CrossRate: 0.01, HoldoutRate: 0.01, Seed: 1}
+	plan := planner.Plan(points)
+	all := len(points) * (len(points) - 1)
+	if len(plan.Probes) >= all/4 {
+		t.Errorf("expected probes much less than %v, got %v", all, len(plan.Probes))
+	}
+	if len(plan.Holdout) == 0 {
+		t.Fatal("expected holdout, got nothing")
+	}
+
+	prober := &Prober{Measurer: NewEngine(graph, 1), Size: 1024, Samples: 1}
+	estimated, report, err := plan.Run(context.Background(), prober, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimated) != all {
+		t.Errorf("expected %v links, got %v", all, len(estimated))
+	}
+	if report.Total.Links != len(plan.Holdout) {
+		t.Errorf("expected %v links in report, got %v", len(plan.Holdout), report.Total.Links)
+	}
+
+	// the estimation is composed by a subset of links, so it's reachable
+	// only if the truth is, and never faster than the truth
+	truth := NewGraph(graph)
+	probed := make(map[Pair]bool)
+	for _, k := range plan.Probes {
+		probed[k] = true
+	}
+	paths := make(map[Point]map[Point]Path)
+	for _, z := range estimated {
+		if probed[Pair{z.A, z.B}] {
+			if _, ok := truth.Edge(z.A, z.B); ok != (z.PacketLoss < 100) {
+				t.Errorf("expected reachable %v from %v to %v, got %v%% packet loss", ok, z.A, z.B, z.PacketLoss)
+			}
+			continue
+		}
+		if paths[z.A] == nil {
+			paths[z.A] = truth.ShortestPaths(z.A)
+		}
+		path, ok := paths[z.A][z.B]
+		if ok != (z.PacketLoss < 100) {
+			t.Errorf("expected reachable %v from %v to %v, got %v%% packet loss", ok, z.A, z.B, z.PacketLoss)
+			continue
+		}
+		if p, _ := truth.Profile(path); ok && z.Latency < p.Latency {
+			t.Errorf("expected latency >= %v from %v to %v, got %v", p.Latency, z.A, z.B, z.Latency)
+		}
+	}
+	t.Logf("estimation error on %v holdout pairs: %+v", len(plan.Holdout), report.Total)
+
+	// holdout pairs are never reachable in the hierarchy, so the latency
+	// estimation is checked on a full mesh of links
+	for i := range graph {
+		isolation := Isolate(graph[i].A, graph[i].B)
+		graph[i].Profile = Profile{Latency: time.Duration(isolation * float64(maxLatency))}
+	}
+	prober.Measurer = NewEngine(graph, 1)
+	_, report, err = plan.Run(context.Background(), prober, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.Misclassified != 0 {
+		t.Errorf("expected no misclassified links, got %v", report.Total.Misclassified)
+	}
+	if report.Total.reachable != len(plan.Holdout) {
+		t.Errorf("expected %v reachable holdout pairs, got %v", len(plan.Holdout), report.Total.reachable)
+	}
+	if report.Total.Latency > 10*time.Millisecond {
+		t.Errorf("expected mean latency error within 10ms, got %v", report.Total.Latency)
+	}
+}
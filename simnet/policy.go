@@ -0,0 +1,137 @@
+package simnet
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Policy is a routing policy choosing paths on a graph.
+type Policy interface {
+	// Routes returns the chosen paths from given point to all reachable
+	// points, including the point itself.
+	Routes(g *Graph, from Point) map[Point]Path
+}
+
+// ShortestPath is the policy choosing paths of the least latency.
+type ShortestPath struct{}
+
+// Routes implements Policy.
+func (ShortestPath) Routes(g *Graph, from Point) map[Point]Path {
+	return g.ShortestPaths(from)
+}
+
+// TierTree is the policy choosing paths of the least latency among ones
+// climbing up tiers and then going down, i.e. a path never goes up after
+// going down, so traffic between regions passes through upper tiers.
+type TierTree struct{}
+
+// tierState is a point reached by a path, which has gone down or not.
+type tierState struct {
+	point Point
+	down  bool
+}
+
+// Routes implements Policy.
+func (TierTree) Routes(g *Graph, from Point) map[Point]Path {
+	start := tierState{from, false}
+	dist := map[tierState]time.Duration{start: 0}
+	prev := make(map[tierState]tierState)
+	done := make(map[tierState]bool)
+	q := &tierQueue{{start, 0}}
+	for q.Len() > 0 {
+		s := heap.Pop(q).(tierItem).state
+		if done[s] {
+			continue
+		}
+		done[s] = true
+
+		for b, p := range g.edges[s.point] {
+			x, y := Tier(s.point.City.Name), Tier(b.City.Name)
+			if s.down && y < x {
+				continue
+			}
+			next := tierState{b, s.down || y > x}
+			if d, ok := dist[next]; !done[next] && (!ok || dist[s]+p.Latency < d) {
+				dist[next] = dist[s] + p.Latency
+				prev[next] = s
+				heap.Push(q, tierItem{next, dist[next]})
+			}
+		}
+	}
+
+	paths := make(map[Point]Path)
+	best := make(map[Point]time.Duration)
+	for s, d := range dist {
+		if v, ok := best[s.point]; ok && v <= d {
+			continue
+		}
+		best[s.point] = d
+
+		var path Path
+		for v := s; v != start; v = prev[v] {
+			path = append(path, v.point)
+		}
+		path = append(path, from)
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+		paths[s.point] = path
+	}
+	return paths
+}
+
+type tierItem struct {
+	state    tierState
+	distance time.Duration
+}
+
+type tierQueue []tierItem
+
+func (q tierQueue) Len() int            { return len(q) }
+func (q tierQueue) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q tierQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *tierQueue) Push(x interface{}) { *q = append(*q, x.(tierItem)) }
+func (q *tierQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// RouteTable contains paths keyed by ordered pairs of different points.
+type RouteTable map[Pair]Path
+
+// Routes computes the route table of all reachable pairs by the policy.
+func Routes(g *Graph, policy Policy) RouteTable {
+	t := make(RouteTable)
+	for _, a := range g.Points() {
+		for b, path := range policy.Routes(g, a) {
+			if a != b {
+				t[Pair{a, b}] = path
+			}
+		}
+	}
+	return t
+}
+
+// Disjoint is the policy choosing the primary paths of disjoint path pairs
+// among the K shortest paths, so that backups are available for failover, or
+// the shortest paths if no such pair.
+type Disjoint struct {
+	K            int
+	Disjointness Disjointness
+}
+
+// Routes implements Policy.
+func (d Disjoint) Routes(g *Graph, from Point) map[Point]Path {
+	paths := g.ShortestPaths(from)
+	for b := range paths {
+		if b == from {
+			continue
+		}
+		if primary, _, ok := g.DisjointPaths(from, b, d.K, d.Disjointness); ok {
+			paths[b] = primary.Path
+		}
+	}
+	return paths
+}
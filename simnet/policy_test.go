@@ -0,0 +1,62 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTierTree(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["宁波市"], ISP: 1}
+	c := Point{City: cities["南京市"], ISP: 1}
+	d := Point{City: cities["上海市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: b, B: c, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: a, B: d, Profile: Profile{Latency: 30 * time.Millisecond}},
+		{A: d, B: c, Profile: Profile{Latency: 30 * time.Millisecond}},
+	})
+
+	if path := (ShortestPath{}).Routes(g, a)[c]; len(path) != 3 || path[1] != b {
+		t.Errorf("expected shortest path through %v, got %v", b, path)
+	}
+	if path := (TierTree{}).Routes(g, a)[c]; len(path) != 3 || path[1] != d {
+		t.Errorf("expected tier tree path through %v, got %v", d, path)
+	}
+	if path := (TierTree{}).Routes(g, a)[b]; len(path) != 2 {
+		t.Errorf("expected direct path going down, got %v", path)
+	}
+
+	routes := Routes(g, TierTree{})
+	if len(routes) != 5 {
+		t.Errorf("expected 5 routes, got %v", routes)
+	}
+	if _, ok := routes[Pair{b, d}]; ok {
+		t.Errorf("expected %v unreachable from %v", d, b)
+	}
+}
+
+func TestDisjoint(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["昆明市"], ISP: 1}
+	x := Point{City: cities["上海市"], ISP: 1}
+	y := Point{City: cities["北京市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: x, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: x, B: y, Profile: Profile{Latency: 1 * time.Millisecond}},
+		{A: y, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: x, B: b, Profile: Profile{Latency: 20 * time.Millisecond}},
+		{A: a, B: y, Profile: Profile{Latency: 20 * time.Millisecond}},
+	})
+
+	if path := (ShortestPath{}).Routes(g, a)[b]; len(path) != 4 {
+		t.Errorf("expected shortest path through %v and %v, got %v", x, y, path)
+	}
+	paths := Disjoint{K: 4}.Routes(g, a)
+	if path := paths[b]; len(path) != 3 {
+		t.Errorf("expected primary path of a disjoint pair, got %v", path)
+	}
+	if path := paths[x]; len(path) != 2 {
+		t.Errorf("expected the shortest path without disjoint pairs, got %v", path)
+	}
+}
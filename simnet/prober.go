@@ -0,0 +1,160 @@
+package simnet
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is the measured result of a pair of points.
+type Result struct {
+	A Point
+	B Point
+	// Samples is the number of samples, including failures.
+	Samples int
+	// Failures is the number of failed samples.
+	Failures int
+	// Connect is the mean connecting time of successful samples.
+	Connect time.Duration
+	// FirstByte is the mean time to first byte of successful samples.
+	FirstByte time.Duration
+	// Throughput is the mean throughput in kbit/s of successful samples.
+	Throughput float64
+
+	// RTTs are round-trip times of successful samples in order.
+	RTTs []time.Duration
+	// Throughputs are throughputs of successful samples in order.
+	Throughputs []float64
+	// Losses tells whether each sample is failed in order.
+	Losses []bool
+}
+
+// SuccessRate returns the ratio of successful samples.
+func (r Result) SuccessRate() float64 {
+	if r.Samples == 0 {
+		return 0
+	}
+	return float64(r.Samples-r.Failures) / float64(r.Samples)
+}
+
+// add accumulates a sample into the result.
+func (r *Result) add(s Sample, err error) {
+	r.Samples++
+	r.Losses = append(r.Losses, err != nil)
+	if err != nil {
+		r.Failures++
+		return
+	}
+
+	r.RTTs = append(r.RTTs, s.FirstByte-s.Connect)
+	r.Throughputs = append(r.Throughputs, s.Throughput())
+	n := time.Duration(r.Samples - r.Failures)
+	r.Connect += (s.Connect - r.Connect) / n
+	r.FirstByte += (s.FirstByte - r.FirstByte) / n
+	r.Throughput += (s.Throughput() - r.Throughput) / float64(n)
+}
+
+// Matrix contains measured results keyed by pairs of points.
+type Matrix map[Pair]*Result
+
+// Prober measures every ordered pair of points.
+type Prober struct {
+	// Measurer is used to measure each pair, e.g. a Network or an Engine.
+	Measurer Measurer
+	// Size is the downloading size in bytes of each sample.
+	Size int
+	// Samples is the number of samples of each pair, 1 if non-positive.
+	Samples int
+	// Concurrency is the maximum number of concurrent measurements, 1 if
+	// non-positive.
+	Concurrency int
+}
+
+// Probe measures all ordered pairs of given points, samples are taken in
+// rounds so that the samples of a pair are spread out over time.
+func (p *Prober) Probe(ctx context.Context, points []Point) (Matrix, error) {
+	var pairs []Pair
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				pairs = append(pairs, Pair{a, b})
+			}
+		}
+	}
+	return p.ProbePairs(ctx, pairs)
+}
+
+// ProbePairs measures given pairs of points.
+func (p *Prober) ProbePairs(ctx context.Context, pairs []Pair) (Matrix, error) {
+	samples, concurrency := p.Samples, p.Concurrency
+	if samples < 1 {
+		samples = 1
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// samples of a pair may complete out of order, so they are kept by
+	// index and accumulated in order after all done
+	type job struct {
+		pair  Pair
+		index int
+	}
+	type outcome struct {
+		sample Sample
+		err    error
+		done   bool
+	}
+	outcomes := make(map[Pair][]outcome, len(pairs))
+	for _, k := range pairs {
+		outcomes[k] = make([]outcome, samples)
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		jobs = make(chan job)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				s, err := p.Measurer.Measure(ctx, j.pair.A, j.pair.B, p.Size)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				outcomes[j.pair][j.index] = outcome{s, err, true}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < samples; i++ {
+		for _, k := range pairs {
+			select {
+			case jobs <- job{k, i}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	m := make(Matrix, len(pairs))
+	for _, k := range pairs {
+		r := &Result{A: k.A, B: k.B}
+		for _, o := range outcomes[k] {
+			if o.done {
+				r.add(o.sample, o.err)
+			}
+		}
+		m[k] = r
+	}
+
+	return m, ctx.Err()
+}
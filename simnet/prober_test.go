@@ -0,0 +1,79 @@
+package simnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProber(t *testing.T) {
+	t.Run("Engine", func(t *testing.T) {
+		var points []Point
+		for i := 0; i < 30; i++ {
+			points = append(points, NewPoint(i*7))
+		}
+		graph := NewAffinity(points)
+		p := &Prober{Measurer: NewEngine(graph, 1), Size: 1024, Samples: 20, Concurrency: 4}
+		m, err := p.Probe(context.Background(), points)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m) != len(graph) {
+			t.Fatalf("expected %v pairs, got %v", len(graph), len(m))
+		}
+
+		for _, z := range graph {
+			r := m[Pair{z.A, z.B}]
+			if r.Samples != 20 {
+				t.Errorf("expected 20 samples from %v to %v, got %v", z.A, z.B, r.Samples)
+			}
+			if z.PacketLoss == 100 && r.SuccessRate() != 0 {
+				t.Errorf("expected unreachable from %v to %v, got success rate %v", z.A, z.B, r.SuccessRate())
+			}
+			if z.PacketLoss == 0 && r.SuccessRate() != 1 {
+				t.Errorf("expected reachable from %v to %v, got success rate %v", z.A, z.B, r.SuccessRate())
+			}
+			if r.Failures < r.Samples && r.FirstByte != z.Latency {
+				t.Errorf("expected first byte after %v from %v to %v, got %v", z.Latency, z.A, z.B, r.FirstByte)
+			}
+		}
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		network, err := NewNetwork(context.Background(), 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer network.Close()
+
+		points := network.Points()
+		for _, a := range points {
+			for _, b := range points {
+				if a != b {
+					network.SetProfile(a, b, Profile{Latency: 10 * time.Millisecond})
+				}
+			}
+		}
+		network.SetProfile(points[0], points[1], Profile{PacketLoss: 100})
+
+		p := &Prober{Measurer: network, Size: 1024, Samples: 3, Concurrency: 8}
+		m, err := p.Probe(context.Background(), points)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, r := range m {
+			if k == (Pair{points[0], points[1]}) {
+				if r.SuccessRate() != 0 {
+					t.Errorf("expected unreachable from %v to %v, got success rate %v", k.A, k.B, r.SuccessRate())
+				}
+				continue
+			}
+			if r.SuccessRate() != 1 {
+				t.Errorf("expected reachable from %v to %v, got success rate %v", k.A, k.B, r.SuccessRate())
+			}
+			if r.FirstByte < 10*time.Millisecond {
+				t.Errorf("expected first byte after 10ms from %v to %v, got %v", k.A, k.B, r.FirstByte)
+			}
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package simnet
+
+// Reachability is the reachability analysis of a graph.
+type Reachability struct {
+	// Components are strongly connected components in reverse topological
+	// order, i.e. no component reaches a later one.
+	Components [][]Point
+	// Isolated are points which neither reach nor are reached by any other
+	// point.
+	Isolated []Point
+	// Relayed are ordered pairs without a direct link but reachable through
+	// relays.
+	Relayed []Pair
+	// Hops is the minimum number of links of each reachable ordered pair of
+	// different points.
+	Hops map[Pair]int
+
+	weak int
+}
+
+// StronglyConnected reports whether any point reaches any other point.
+func (r *Reachability) StronglyConnected() bool {
+	return len(r.Components) <= 1
+}
+
+// Connected reports whether the graph is connected ignoring directions of
+// links.
+func (r *Reachability) Connected() bool {
+	return r.weak <= 1
+}
+
+// Reachable reports whether a reaches b.
+func (r *Reachability) Reachable(a, b Point) bool {
+	_, ok := r.Hops[Pair{a, b}]
+	return a == b || ok
+}
+
+// Unreachable returns ordered pairs of points in components which don't reach
+// each other.
+func (r *Reachability) Unreachable() []Pair {
+	var pairs []Pair
+	for _, x := range r.Components {
+		for _, y := range r.Components {
+			for _, a := range x {
+				for _, b := range y {
+					if a != b && !r.Reachable(a, b) {
+						pairs = append(pairs, Pair{a, b})
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// AnalyzeReachability computes strongly connected components by Tarjan's
+// algorithm and minimum hop counts by breadth-first search from each point.
+func AnalyzeReachability(g *Graph) *Reachability {
+	r := &Reachability{Hops: make(map[Pair]int)}
+	t := &tarjan{graph: g, index: make(map[Point]int), low: make(map[Point]int), stacked: make(map[Point]bool)}
+	for _, p := range g.Points() {
+		if _, ok := t.index[p]; !ok {
+			t.connect(p)
+		}
+	}
+	r.Components = t.components
+
+	reached := make(map[Point]bool)
+	for _, a := range g.Points() {
+		hops := map[Point]int{a: 0}
+		queue := []Point{a}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for _, b := range g.neighbors(v) {
+				if _, ok := hops[b]; !ok {
+					hops[b] = hops[v] + 1
+					queue = append(queue, b)
+				}
+			}
+		}
+
+		for _, b := range g.Points() {
+			if n, ok := hops[b]; ok && a != b {
+				r.Hops[Pair{a, b}] = n
+				reached[a], reached[b] = true, true
+				if n > 1 {
+					r.Relayed = append(r.Relayed, Pair{a, b})
+				}
+			}
+		}
+	}
+	for _, p := range g.Points() {
+		if !reached[p] {
+			r.Isolated = append(r.Isolated, p)
+		}
+	}
+
+	// weakly connected components are counted by union-find of components
+	parent := make(map[Point]Point)
+	var find func(p Point) Point
+	find = func(p Point) Point {
+		if q, ok := parent[p]; ok && q != p {
+			parent[p] = find(q)
+			return parent[p]
+		}
+		return p
+	}
+	r.weak = len(g.Points())
+	for _, a := range g.Points() {
+		for _, b := range g.neighbors(a) {
+			if x, y := find(a), find(b); x != y {
+				parent[x] = y
+				r.weak--
+			}
+		}
+	}
+	return r
+}
+
+// tarjan is the state of Tarjan's strongly connected components algorithm.
+type tarjan struct {
+	graph      *Graph
+	index      map[Point]int
+	low        map[Point]int
+	stack      []Point
+	stacked    map[Point]bool
+	components [][]Point
+}
+
+func (t *tarjan) connect(v Point) {
+	t.index[v] = len(t.index)
+	t.low[v] = t.index[v]
+	t.stack = append(t.stack, v)
+	t.stacked[v] = true
+
+	for _, w := range t.graph.neighbors(v) {
+		if _, ok := t.index[w]; !ok {
+			t.connect(w)
+			if t.low[w] < t.low[v] {
+				t.low[v] = t.low[w]
+			}
+		} else if t.stacked[w] && t.index[w] < t.low[v] {
+			t.low[v] = t.index[w]
+		}
+	}
+
+	if t.low[v] == t.index[v] {
+		var component []Point
+		for {
+			w := t.stack[len(t.stack)-1]
+			t.stack = t.stack[:len(t.stack)-1]
+			t.stacked[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		t.components = append(t.components, component)
+	}
+}
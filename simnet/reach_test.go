@@ -0,0 +1,65 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzeReachability(t *testing.T) {
+	a := Point{City: cities["北京市"], ISP: 1}
+	b := Point{City: cities["上海市"], ISP: 1}
+	c := Point{City: cities["广州市"], ISP: 1}
+	d := Point{City: cities["成都市"], ISP: 1}
+	r := AnalyzeReachability(NewGraph(Affinity{
+		{A: a, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: b, B: a, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: b, B: c, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: c, B: d, Profile: Profile{PacketLoss: 100}},
+	}))
+
+	if len(r.Components) != 3 || len(r.Components[2]) != 1 || r.Components[2][0] != d {
+		t.Errorf("expected 3 components, got %v", r.Components)
+	}
+	if len(r.Components[0]) != 1 || r.Components[0][0] != c {
+		t.Errorf("expected %v ordered first, got %v", c, r.Components)
+	}
+	if r.StronglyConnected() || r.Connected() {
+		t.Errorf("expected disconnected")
+	}
+	if len(r.Isolated) != 1 || r.Isolated[0] != d {
+		t.Errorf("expected %v isolated, got %v", d, r.Isolated)
+	}
+	if len(r.Relayed) != 1 || r.Relayed[0] != (Pair{a, c}) || r.Hops[Pair{a, c}] != 2 {
+		t.Errorf("expected %v to %v relayed by 2 hops, got %v", a, c, r.Relayed)
+	}
+	if !r.Reachable(a, c) || r.Reachable(c, a) {
+		t.Errorf("expected %v reaching %v only", a, c)
+	}
+	if n := len(r.Unreachable()); n != 12-4 {
+		t.Errorf("expected 8 unreachable pairs, got %v", n)
+	}
+
+	t.Run("Cores are reachable from everywhere", func(t *testing.T) {
+		var points, cores []Point
+		for _, name := range names {
+			if Tier(name) <= 3 {
+				points = append(points, Point{City: cities[name], ISP: isps[0]})
+			}
+			if Tier(name) == 1 {
+				cores = append(cores, points[len(points)-1])
+			}
+		}
+
+		r := AnalyzeReachability(NewGraph(NewAffinity(points)))
+		if r.StronglyConnected() || !r.Connected() || len(r.Isolated) > 0 {
+			t.Errorf("expected connected but not strongly, got %v components", len(r.Components))
+		}
+		for _, c := range cores {
+			for _, p := range points {
+				if !r.Reachable(p, c) {
+					t.Errorf("expected %v reaching %v", p, c)
+				}
+			}
+		}
+	})
+}
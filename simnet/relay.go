@@ -0,0 +1,250 @@
+package simnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RouteHeader is the HTTP header carrying comma separated ports of the
+	// rest hops to relay, where the last one is the destination.
+	RouteHeader = "X-Simnet-Route"
+	// TraceHeader is the HTTP header turning on the trace mode if non-empty,
+	// in which every hop is recorded by the HopHeader of response.
+	TraceHeader = "X-Simnet-Trace"
+	// HopHeader is the HTTP header of response carrying a hop in JSON, which
+	// is added by every hop in order.
+	HopHeader = "X-Simnet-Hop"
+	// DestinationHeader is the HTTP header carrying the port of destination,
+	// a request with which is forwarded by forwarding tables hop by hop.
+	DestinationHeader = "X-Simnet-Destination"
+	// HopLimitHeader is the HTTP header carrying the number of hops a
+	// request can still be forwarded by tables, which is maxHops by default.
+	HopLimitHeader = "X-Simnet-Hop-Limit"
+)
+
+// maxHops is the default hop limit of forwarding by tables, which stops
+// forwarding loops of inconsistent tables.
+const maxHops = 16
+
+// Hop is a server which a request passes through.
+type Hop struct {
+	Port  int
+	Point Point
+	// Arrived is the time when the request arrives.
+	Arrived time.Time
+	// Forwarded is the time when the request is forwarded to the next hop or
+	// served, after the link from the previous hop is applied.
+	Forwarded time.Time
+}
+
+// Latency returns the latency contributed by the link to the hop.
+func (h Hop) Latency() time.Duration {
+	return h.Forwarded.Sub(h.Arrived)
+}
+
+func addHop(h http.Header, hop Hop) {
+	b, _ := json.Marshal(hop)
+	h.Add(HopHeader, string(b))
+}
+
+// relay forwards the request to the next hop of route.
+func (n *Network) relay(hop Hop, w http.ResponseWriter, r *http.Request) {
+	route := strings.Split(r.Header.Get(RouteHeader), ",")
+	next, err := strconv.Atoi(route[0])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid route: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	h := r.Header.Clone()
+	if len(route) > 1 {
+		h.Set(RouteHeader, strings.Join(route[1:], ","))
+	} else {
+		h.Del(RouteHeader)
+	}
+	n.forward(hop, w, r, h, []int{next})
+}
+
+// forwardByTable forwards the request to the next hop of destination by the
+// forwarding table of server.
+func (n *Network) forwardByTable(hop Hop, w http.ResponseWriter, r *http.Request) {
+	dest, err := strconv.Atoi(r.Header.Get(DestinationHeader))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid destination: %v", err), http.StatusBadRequest)
+		return
+	}
+	limit := maxHops
+	if v := r.Header.Get(HopLimitHeader); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid hop limit: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if limit <= 0 {
+		http.Error(w, "hop limit exceeded", http.StatusLoopDetected)
+		return
+	}
+
+	next := n.nextHops(hop.Port, dest)
+	if len(next) == 0 {
+		http.Error(w, fmt.Sprintf("no route to %d", dest), http.StatusBadGateway)
+		return
+	}
+	if flow := r.Header.Get(MultipathHeader); flow != "" {
+		// rotates candidates so that the hashed one is tried first
+		i := hashFlow(fmt.Sprintf("%s/%d", flow, hop.Port), len(next))
+		next = append(next[i:], next[:i]...)
+	}
+	h := r.Header.Clone()
+	h.Set(HopLimitHeader, strconv.Itoa(limit-1))
+	n.forward(hop, w, r, h, next)
+}
+
+// forward sends the request with given header to the first reachable one of
+// next hops in order, then copies the response back.
+func (n *Network) forward(hop Hop, w http.ResponseWriter, r *http.Request, h http.Header, next []int) {
+	h.Set(FromHeader, fmt.Sprint(hop.Port))
+	for _, port := range next {
+		req, err := http.NewRequest(r.Method, fmt.Sprintf("http://127.0.0.1:%d%s", port, r.URL.RequestURI()), r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Header = h
+
+		res, err := http.DefaultClient.Do(req.WithContext(r.Context()))
+		if err != nil {
+			if r.Context().Err() != nil {
+				break
+			}
+			continue
+		}
+		defer res.Body.Close()
+
+		if r.Header.Get(TraceHeader) != "" {
+			addHop(w.Header(), hop)
+		}
+		for k, vs := range res.Header {
+			for _, v := range vs {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(res.StatusCode)
+		if _, err := io.Copy(w, res.Body); err != nil {
+			// passes the reset of next hop on
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
+	// all next hops are unreachable, so is the destination
+	panic(http.ErrAbortHandler)
+}
+
+// Dialer sends requests on behalf of a server of network, which are relayed
+// by routes.
+type Dialer struct {
+	Network *Network
+	// From is the port of the server sending requests.
+	From int
+	// Flow is the flow key of requests forwarded by tables if non-empty, see
+	// MultipathHeader.
+	Flow string
+}
+
+// Get requests the path through the route, which consists of ports of
+// relays and the destination.
+func (d *Dialer) Get(ctx context.Context, route []int, path string) (*http.Response, error) {
+	req, err := d.request(route, path)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req.WithContext(ctx))
+}
+
+// Trace requests the path through the route in trace mode, it returns all
+// passed hops in order.
+func (d *Dialer) Trace(ctx context.Context, route []int, path string) ([]Hop, error) {
+	req, err := d.request(route, path)
+	if err != nil {
+		return nil, err
+	}
+	return trace(ctx, req)
+}
+
+// trace sends the request in trace mode, it returns all passed hops in order.
+func trace(ctx context.Context, req *http.Request) ([]Hop, error) {
+	req.Header.Set(TraceHeader, "1")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+		return nil, err
+	}
+
+	var hops []Hop
+	for _, v := range res.Header[HopHeader] {
+		var hop Hop
+		if err := json.Unmarshal([]byte(v), &hop); err != nil {
+			return nil, err
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// Forward requests the path from the destination port, which is forwarded by
+// forwarding tables starting from the server of dialer.
+func (d *Dialer) Forward(ctx context.Context, to int, path string) (*http.Response, error) {
+	req, err := d.request([]int{d.From}, path)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(DestinationHeader, fmt.Sprint(to))
+	return http.DefaultClient.Do(req.WithContext(ctx))
+}
+
+// TraceForward is like Forward in trace mode, it returns all passed hops in
+// order, starting from the server of dialer.
+func (d *Dialer) TraceForward(ctx context.Context, to int, path string) ([]Hop, error) {
+	req, err := d.request([]int{d.From}, path)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(DestinationHeader, fmt.Sprint(to))
+	return trace(ctx, req)
+}
+
+func (d *Dialer) request(route []int, path string) (*http.Request, error) {
+	if len(route) == 0 {
+		return nil, fmt.Errorf("empty route")
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d%s", route[0], path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(FromHeader, fmt.Sprint(d.From))
+	if d.Flow != "" {
+		req.Header.Set(MultipathHeader, d.Flow)
+	}
+	if len(route) > 1 {
+		var ports []string
+		for _, port := range route[1:] {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		req.Header.Set(RouteHeader, strings.Join(ports, ","))
+	}
+	return req, nil
+}
@@ -0,0 +1,81 @@
+package simnet
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestDialer(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	points := network.Points()
+	if len(points) < 4 {
+		t.Skip("expected at least 4 distinct points, got", len(points))
+	}
+	var ports []int
+	for _, p := range points[:4] {
+		port, _ := network.port(p)
+		ports = append(ports, port)
+	}
+	latencies := []time.Duration{30 * time.Millisecond, 20 * time.Millisecond, 10 * time.Millisecond}
+	for i, d := range latencies {
+		network.SetProfile(points[i], points[i+1], Profile{Latency: d})
+	}
+
+	d := &Dialer{Network: network, From: ports[0]}
+	route := ports[1:]
+
+	t.Run("Get", func(t *testing.T) {
+		since := time.Now()
+		res, err := d.Get(context.Background(), route, "/4k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 4096 {
+			t.Errorf("expected 4k bytes, got %v", n)
+		}
+		if elapsed := time.Since(since); elapsed < 60*time.Millisecond {
+			t.Errorf("expected elapse >= 60ms, got %v", elapsed)
+		}
+	})
+
+	t.Run("Trace", func(t *testing.T) {
+		hops, err := d.Trace(context.Background(), route, "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hops) != 3 {
+			t.Fatalf("expected 3 hops, got %v", len(hops))
+		}
+		for i, hop := range hops {
+			if hop.Port != route[i] || hop.Point != points[i+1] {
+				t.Errorf("expected hop %v at %v, got %v at %v", route[i], points[i+1], hop.Port, hop.Point)
+			}
+			if hop.Latency() < latencies[i] {
+				t.Errorf("expected latency of hop %v >= %v, got %v", i, latencies[i], hop.Latency())
+			}
+			if i > 0 && hop.Arrived.Before(hops[i-1].Forwarded) {
+				t.Errorf("expected hop %v arrived after hop %v forwarded", i, i-1)
+			}
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		network.SetProfile(points[1], points[2], Profile{PacketLoss: 100})
+		if _, err := d.Trace(context.Background(), route, "/1k"); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Event is a fault lasting from Start to End since a scenario begins, a zero
+// End means lasting until the scenario ends, otherwise End must be after
+// Start.
+type Event struct {
+	Start time.Duration
+	End   time.Duration
+	Fault Fault
+}
+
+// Active reports whether the event is active at time t.
+func (e Event) Active(t time.Duration) bool {
+	return t >= e.Start && (e.End == 0 || t < e.End)
+}
+
+// Scenario is a timeline of events.
+type Scenario []Event
+
+// Duration returns the time when all events end, an open-ended event counts
+// by its Start since it lasts as long as the scenario runs.
+func (s Scenario) Duration() time.Duration {
+	var d time.Duration
+	for _, e := range s {
+		if e.Start > d {
+			d = e.Start
+		}
+		if e.End > d {
+			d = e.End
+		}
+	}
+	return d
+}
+
+// At returns the affinity at time t since the scenario begins.
+func (s Scenario) At(t time.Duration, a Affinity) Affinity {
+	r := make(Affinity, len(a))
+	for i, z := range a {
+		for _, e := range s {
+			if e.Active(t) {
+				z.Profile = e.Fault.Apply(z.A, z.B, z.Profile)
+			}
+		}
+		r[i] = z
+	}
+	return r
+}
+
+// Run applies events to the network on the timeline of the network clock.
+// It blocks until the scenario ends or ctx is done, faults injected by events
+// are cleared before returning. A scenario with open-ended events never ends
+// by itself, so Run keeps them active until ctx is done. An error is returned
+// without applying any events if an event ends before it starts.
+func (s Scenario) Run(ctx context.Context, n *Network) error {
+	for i, e := range s {
+		if e.End != 0 && e.End <= e.Start {
+			return fmt.Errorf("event %v: end %v is not after start %v", i, e.End, e.Start)
+		}
+	}
+
+	type action struct {
+		at    time.Duration
+		event int
+		start bool
+	}
+
+	var actions []action
+	for i, e := range s {
+		actions = append(actions, action{e.Start, i, true})
+		if e.End > 0 {
+			actions = append(actions, action{e.End, i, false})
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].at < actions[j].at
+	})
+
+	ids := make(map[int]int)
+	defer func() {
+		for _, id := range ids {
+			n.Clear(id)
+		}
+	}()
+
+	clock := n.Clock()
+	since := clock.Now()
+	for _, a := range actions {
+		select {
+		case <-clock.After(a.at - clock.Now().Sub(since)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if a.start {
+			ids[a.event] = n.Inject(s[a.event].Fault)
+		} else if id, ok := ids[a.event]; ok {
+			n.Clear(id)
+			delete(ids, a.event)
+		}
+	}
+
+	if len(ids) > 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
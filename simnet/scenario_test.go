@@ -0,0 +1,153 @@
+package simnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScenario(t *testing.T) {
+	var points []Point
+	for i := 0; i < len(names); i++ {
+		points = append(points, NewPoint(i))
+	}
+	g := NewAffinity(points)
+	core := Selector{City: "北京市"}
+	isp := points[0].ISP
+
+	s := Scenario{
+		{Start: 30 * time.Second, End: 90 * time.Second, Fault: CutOff(Selector{Province: "广东省", ISP: isp})},
+		{Start: 60 * time.Second, Fault: Degrade(core, Profile{PacketLoss: 20})},
+		{End: 30 * time.Second, Fault: CutPeering(points[1].ISP, points[2].ISP)},
+	}
+	if d := s.Duration(); d != 90*time.Second {
+		t.Errorf("expected duration 90s, got %v", d)
+	}
+
+	t.Run("Affinity at time", func(t *testing.T) {
+		for _, tc := range []struct {
+			at                   time.Duration
+			outage, degrade, cut bool
+		}{
+			{0, false, false, true},
+			{30 * time.Second, true, false, false},
+			{60 * time.Second, true, true, false},
+			{90 * time.Second, false, true, false},
+		} {
+			for _, z := range s.At(tc.at, g) {
+				inA, inB := s[0].Fault.A.Match(z.A), s[0].Fault.A.Match(z.B)
+				if tc.outage && inA != inB && z.PacketLoss != 100 {
+					t.Errorf("at %v: expected %v to %v unreachable, got %v%% packet loss", tc.at, z.A, z.B, z.PacketLoss)
+				}
+				if tc.degrade && core.Match(z.A) != core.Match(z.B) && z.PacketLoss < 20 {
+					t.Errorf("at %v: expected %v to %v degraded, got %v%% packet loss", tc.at, z.A, z.B, z.PacketLoss)
+				}
+				if tc.cut && (z.A.ISP == points[1].ISP && z.B.ISP == points[2].ISP) && z.PacketLoss != 100 {
+					t.Errorf("at %v: expected %v to %v unreachable, got %v%% packet loss", tc.at, z.A, z.B, z.PacketLoss)
+				}
+			}
+		}
+	})
+
+	t.Run("Run on network", func(t *testing.T) {
+		network, err := NewNetwork(context.Background(), 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer network.Close()
+
+		ports := network.Ports()
+		a, _ := network.Point(ports[0])
+		var b Point
+		for _, port := range ports {
+			if p, _ := network.Point(port); p.City.Province != a.City.Province {
+				b = p
+			}
+		}
+		if b == (Point{}) {
+			t.Skip("all servers are within", a.City.Province)
+		}
+
+		clock := NewSimClock(time.Now())
+		network.SetClock(clock)
+		waitFor := func(n int) {
+			for clock.Waiters() != n {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		s := Scenario{{Start: 30 * time.Second, End: 90 * time.Second, Fault: CutOff(Selector{Province: a.City.Province})}}
+		done := make(chan error)
+		go func() { done <- s.Run(context.Background(), network) }()
+
+		waitFor(1)
+		clock.Advance(60 * time.Second)
+		waitFor(1)
+		if p := network.Profile(a, b); p.PacketLoss != 100 {
+			t.Errorf("expected %v to %v unreachable, got %v%% packet loss", a, b, p.PacketLoss)
+		}
+		clock.Advance(30 * time.Second)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		if len(network.faults) != 0 {
+			t.Errorf("expected no faults, got %v", network.faults)
+		}
+	})
+}
+
+func TestScenarioOpenEnded(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	points := network.Points()
+	a := points[0]
+	var b Point
+	for _, p := range points {
+		if p.City != a.City || p.ISP != a.ISP {
+			b = p
+		}
+	}
+	if b == (Point{}) {
+		t.Skip("all servers are of", a)
+	}
+	network.SetProfile(a, b, Profile{})
+
+	clock := NewSimClock(time.Now())
+	network.SetClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := Scenario{{Start: 10 * time.Second, Fault: Degrade(Selector{City: a.City.Name, ISP: a.ISP}, Profile{Latency: time.Second})}}
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, network) }()
+
+	for clock.Waiters() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected running until canceled, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if p := network.Profile(a, b); p.Latency != time.Second {
+		t.Errorf("expected %v to %v degraded by 1s latency, got %v", a, b, p)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if p := network.Profile(a, b); p != (Profile{}) {
+		t.Errorf("expected %v to %v restored, got %v", a, b, p)
+	}
+
+	invalid := Scenario{{Start: 10 * time.Second, End: 5 * time.Second, Fault: CutOff(Selector{City: a.City.Name})}}
+	if err := invalid.Run(context.Background(), network); err == nil {
+		t.Error("expected an error of ending before starting, got nil")
+	}
+}
@@ -47,14 +47,24 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 // ListenHTTP creates a PORT-unspecified HTTP server.
 // If success it returns the underlying port and a nil error.
 func ListenHTTP(ctx context.Context) (int, error) {
+	return listenHTTP(ctx, func(int) http.HandlerFunc { return Handler })
+}
+
+// listenHTTP is like ListenHTTP but serves the handler made by given function,
+// which is called with the underlying port before serving.
+func listenHTTP(ctx context.Context, handler func(port int) http.HandlerFunc) (int, error) {
 	l, err := net.Listen("tcp", ":0")
 	if err != nil {
 		return 0, err
 	}
 
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	n, _ := strconv.Atoi(port)
+
+	h := handler(n)
 	server := new(http.Server)
 	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		Handler(w, r.WithContext(ctx))
+		h(w, r.WithContext(ctx))
 	})
 	go server.Serve(l)
 	go func() {
@@ -63,8 +73,6 @@ func ListenHTTP(ctx context.Context) (int, error) {
 		server.Close()
 	}()
 
-	_, port, _ := net.SplitHostPort(l.Addr().String())
-	n, _ := strconv.Atoi(port)
 	return n, nil
 }
 
@@ -0,0 +1,139 @@
+package simnet
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Interval is a confidence interval.
+type Interval struct {
+	Low, High float64
+}
+
+// Separated reports whether two intervals don't overlap.
+func (i Interval) Separated(o Interval) bool {
+	return i.High < o.Low || o.High < i.Low
+}
+
+// zScore returns the two-sided standard normal quantile of the confidence
+// level, e.g. 1.96 for 0.95.
+func zScore(level float64) float64 {
+	return math.Sqrt2 * math.Erfinv(level)
+}
+
+// Percentile returns the q-th (0 to 100) percentile of round-trip times by
+// the nearest rank.
+func (r Result) Percentile(q float64) time.Duration {
+	if len(r.RTTs) == 0 {
+		return 0
+	}
+
+	rtts := append([]time.Duration(nil), r.RTTs...)
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	i := int(math.Ceil(q/100*float64(len(rtts)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return rtts[i]
+}
+
+// RTTInterval returns the confidence interval in seconds of the mean
+// round-trip time.
+func (r Result) RTTInterval(level float64) Interval {
+	xs := make([]float64, len(r.RTTs))
+	for i, v := range r.RTTs {
+		xs[i] = v.Seconds()
+	}
+	return meanInterval(xs, level)
+}
+
+// ThroughputStddev returns the standard deviation of throughputs.
+func (r Result) ThroughputStddev() float64 {
+	_, stddev := meanStddev(r.Throughputs)
+	return stddev
+}
+
+// ThroughputInterval returns the confidence interval in kbit/s of the mean
+// throughput.
+func (r Result) ThroughputInterval(level float64) Interval {
+	return meanInterval(r.Throughputs, level)
+}
+
+// SuccessInterval returns the Wilson score interval of the success rate.
+func (r Result) SuccessInterval(level float64) Interval {
+	if r.Samples == 0 {
+		return Interval{0, 1}
+	}
+
+	z, n, p := zScore(level), float64(r.Samples), r.SuccessRate()
+	center := (p + z*z/(2*n)) / (1 + z*z/n)
+	margin := z / (1 + z*z/n) * math.Sqrt(p*(1-p)/n+z*z/(4*n*n))
+	return Interval{math.Max(center-margin, 0), math.Min(center+margin, 1)}
+}
+
+// LossBursts returns lengths of all bursts of consecutive failed samples.
+func (r Result) LossBursts() []int {
+	var bursts []int
+	n := 0
+	for _, lost := range r.Losses {
+		if lost {
+			n++
+		} else if n > 0 {
+			bursts = append(bursts, n)
+			n = 0
+		}
+	}
+	if n > 0 {
+		bursts = append(bursts, n)
+	}
+	return bursts
+}
+
+// FasterThan reports whether the mean round-trip time of r is significantly
+// less than o, i.e. the confidence intervals are separated.
+func (r Result) FasterThan(o Result, level float64) bool {
+	x, y := r.RTTInterval(level), o.RTTInterval(level)
+	return x.Separated(y) && x.High < y.Low
+}
+
+// MoreReliableThan reports whether the success rate of r is significantly
+// higher than o, i.e. the confidence intervals are separated.
+func (r Result) MoreReliableThan(o Result, level float64) bool {
+	x, y := r.SuccessInterval(level), o.SuccessInterval(level)
+	return x.Separated(y) && x.Low > y.High
+}
+
+func meanStddev(xs []float64) (float64, float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	if len(xs) == 1 {
+		return mean, 0
+	}
+
+	var sq float64
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(xs)-1))
+}
+
+// meanInterval returns the normal approximated confidence interval of the
+// mean, which is unbounded if less than 2 values.
+func meanInterval(xs []float64, level float64) Interval {
+	z := zScore(level)
+	if len(xs) < 2 {
+		return Interval{math.Inf(-1), math.Inf(1)}
+	}
+
+	mean, stddev := meanStddev(xs)
+	margin := z * stddev / math.Sqrt(float64(len(xs)))
+	return Interval{mean - margin, mean + margin}
+}
@@ -0,0 +1,73 @@
+package simnet
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestResultStats(t *testing.T) {
+	var r Result
+	for i, lost := range []bool{false, true, true, false, true, false, false, true, true, true} {
+		var err error
+		if lost {
+			err = errors.New("lost")
+		}
+		r.add(Sample{FirstByte: time.Duration(i+1) * time.Millisecond, Total: time.Duration(i+2) * time.Millisecond, Bytes: 1000}, err)
+	}
+
+	if bursts := r.LossBursts(); len(bursts) != 3 || bursts[0] != 2 || bursts[1] != 1 || bursts[2] != 3 {
+		t.Errorf("expected loss bursts [2 1 3], got %v", bursts)
+	}
+	if p := r.Percentile(50); p != 4*time.Millisecond {
+		t.Errorf("expected p50 4ms, got %v", p)
+	}
+	if p := r.Percentile(99); p != 7*time.Millisecond {
+		t.Errorf("expected p99 7ms, got %v", p)
+	}
+	if v := r.ThroughputStddev(); v != 0 {
+		t.Errorf("expected no throughput stddev, got %v", v)
+	}
+
+	i := r.SuccessInterval(0.95)
+	if i.Low >= 0.4 || i.High <= 0.4 || i.Low < 0 || i.High > 1 {
+		t.Errorf("expected interval around 0.4, got %+v", i)
+	}
+	if z := zScore(0.95); math.Abs(z-1.96) > 0.001 {
+		t.Errorf("expected z-score 1.96, got %v", z)
+	}
+}
+
+func TestSignificance(t *testing.T) {
+	var fast, slow, noisy Result
+	for i := 0; i < 30; i++ {
+		jitter := time.Duration(i%3) * time.Millisecond
+		fast.add(Sample{FirstByte: 10*time.Millisecond + jitter}, nil)
+		slow.add(Sample{FirstByte: 20*time.Millisecond + jitter}, nil)
+		noisy.add(Sample{FirstByte: time.Duration(i%2) * 30 * time.Millisecond}, nil)
+	}
+
+	if !fast.FasterThan(slow, 0.95) {
+		t.Error("expected significantly faster")
+	}
+	if slow.FasterThan(fast, 0.95) {
+		t.Error("expected not faster")
+	}
+	if fast.FasterThan(noisy, 0.95) || noisy.FasterThan(fast, 0.95) {
+		t.Error("expected no significance against noisy samples")
+	}
+
+	var reliable, lossy Result
+	for i := 0; i < 100; i++ {
+		reliable.add(Sample{}, nil)
+		var err error
+		if i%2 == 0 {
+			err = errors.New("lost")
+		}
+		lossy.add(Sample{}, err)
+	}
+	if !reliable.MoreReliableThan(lossy, 0.99) {
+		t.Error("expected significantly more reliable")
+	}
+}
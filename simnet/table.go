@@ -0,0 +1,183 @@
+package simnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Forward is an entry of forwarding table.
+type Forward struct {
+	Destination Point
+	NextHop     Point
+	// Alternates are other next hops reaching the destination without
+	// passing back, in order of latency.
+	Alternates []Point
+}
+
+// ForwardingTable maps destinations to next hops for a point.
+type ForwardingTable struct {
+	Point   Point
+	Entries []Forward
+}
+
+// Lookup returns the entry of given destination.
+func (t ForwardingTable) Lookup(dest Point) (Forward, bool) {
+	i := sort.Search(len(t.Entries), func(i int) bool {
+		return !lessPoint(t.Entries[i].Destination, dest)
+	})
+	if i < len(t.Entries) && t.Entries[i].Destination == dest {
+		return t.Entries[i], true
+	}
+	return Forward{}, false
+}
+
+// sorted returns the table with a copy of entries sorted by destination as
+// Lookup requires.
+func (t ForwardingTable) sorted() ForwardingTable {
+	t.Entries = append([]Forward(nil), t.Entries...)
+	sort.Slice(t.Entries, func(i, j int) bool {
+		return lessPoint(t.Entries[i].Destination, t.Entries[j].Destination)
+	})
+	return t
+}
+
+// BuildTables builds forwarding tables of all points of graph, where next
+// hops are the second points of paths by the policy, and at most given number
+// of alternates are chosen among other neighbors by the latency to the
+// destination through them without passing the point itself.
+//
+// Forwarding hop by hop follows the policy exactly only if the policy is
+// consistent, that is the path of each hop is the rest of the path from the
+// previous hop, which is true for ShortestPath but not always for others.
+func BuildTables(g *Graph, policy Policy, alternates int) []ForwardingTable {
+	tables := make([]ForwardingTable, 0, len(g.Points()))
+	for _, a := range g.Points() {
+		t := ForwardingTable{Point: a}
+
+		// latencies from each neighbor to destinations avoiding a
+		via := make(map[Point]map[Point]time.Duration)
+		if alternates > 0 {
+			for _, v := range g.neighbors(a) {
+				via[v] = make(map[Point]time.Duration)
+				for b, path := range g.shortestPaths(v, func(_, y Point) bool { return y == a }) {
+					p, _ := g.Profile(path)
+					via[v][b] = g.edges[a][v].Latency + p.Latency
+				}
+			}
+		}
+
+		for b, path := range policy.Routes(g, a) {
+			if a == b {
+				continue
+			}
+
+			f := Forward{Destination: b, NextHop: path[1]}
+			var others []Point
+			for _, v := range g.neighbors(a) {
+				if _, ok := via[v][b]; ok && v != f.NextHop {
+					others = append(others, v)
+				}
+			}
+			sort.SliceStable(others, func(i, j int) bool { return via[others[i]][b] < via[others[j]][b] })
+			if len(others) > alternates {
+				others = others[:alternates]
+			}
+			f.Alternates = others
+			t.Entries = append(t.Entries, f)
+		}
+
+		tables = append(tables, t.sorted())
+	}
+	return tables
+}
+
+// LoadTable loads the forwarding table of server on given port. Entries
+// may be in any order.
+func (n *Network) LoadTable(port int, t ForwardingTable) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.servers[port]; !ok {
+		return fmt.Errorf("no server on port %d", port)
+	}
+	n.tables[port] = t.sorted()
+	return nil
+}
+
+// LoadTables loads each table to all servers of its point.
+func (n *Network) LoadTables(tables []ForwardingTable) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, t := range tables {
+		for port, p := range n.servers {
+			if p == t.Point {
+				n.tables[port] = t.sorted()
+			}
+		}
+	}
+}
+
+// Table returns the forwarding table of server on given port.
+func (n *Network) Table(port int) (ForwardingTable, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	t, ok := n.tables[port]
+	return t, ok
+}
+
+// nextHops returns ports of the next hop and alternates from server on given
+// port to the destination port.
+func (n *Network) nextHops(port, dest int) []int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	p, ok := n.servers[dest]
+	if !ok {
+		return nil
+	}
+	f, ok := n.tables[port].Lookup(p)
+	if !ok {
+		return nil
+	}
+
+	var ports []int
+	for _, v := range append([]Point{f.NextHop}, f.Alternates...) {
+		if v == p {
+			ports = append(ports, dest)
+		} else if port, ok := n.ports[v]; ok {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// serveTable returns or loads the forwarding table of server on given port.
+func (n *Network) serveTable(port int, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		t, ok := n.Table(port)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, t)
+	case "PUT":
+		var t ForwardingTable
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := n.LoadTable(port, t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,192 @@
+package simnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestBuildTables(t *testing.T) {
+	a := Point{City: cities["杭州市"], ISP: 1}
+	b := Point{City: cities["昆明市"], ISP: 1}
+	x := Point{City: cities["上海市"], ISP: 1}
+	y := Point{City: cities["北京市"], ISP: 1}
+	g := NewGraph(Affinity{
+		{A: a, B: x, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: a, B: y, Profile: Profile{Latency: 20 * time.Millisecond}},
+		{A: x, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: x, B: a, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: y, B: b, Profile: Profile{Latency: 20 * time.Millisecond}},
+	})
+
+	tables := BuildTables(g, ShortestPath{}, 1)
+	if len(tables) != 4 || tables[0].Point != a {
+		t.Fatalf("expected tables of 4 points, got %v", tables)
+	}
+	f, ok := tables[0].Lookup(b)
+	if !ok || f.NextHop != x || len(f.Alternates) != 1 || f.Alternates[0] != y {
+		t.Errorf("expected next hop %v and alternate %v, got %+v", x, y, f)
+	}
+	if _, ok := tables[0].Lookup(a); ok {
+		t.Errorf("expected no entry of itself")
+	}
+
+	// x can't reach y without passing back a
+	f, _ = BuildTables(g, ShortestPath{}, 1)[1].Lookup(y)
+	if f.NextHop != a || len(f.Alternates) != 0 {
+		t.Errorf("expected next hop %v only, got %+v", a, f)
+	}
+	if f, _ := BuildTables(g, ShortestPath{}, 0)[0].Lookup(b); len(f.Alternates) != 0 {
+		t.Errorf("expected no alternates, got %v", f.Alternates)
+	}
+
+	data, err := json.Marshal(tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []ForwardingTable
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := decoded[0].Lookup(b); !ok || f.NextHop != x {
+		t.Errorf("expected decoded next hop %v, got %+v", x, f)
+	}
+}
+
+func TestForwardByTable(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	points := network.Points()
+	if len(points) < 4 {
+		t.Skip("expected at least 4 distinct points, got", len(points))
+	}
+	points = points[:4]
+	var ports []int
+	for _, p := range points {
+		port, _ := network.port(p)
+		ports = append(ports, port)
+	}
+
+	var chain Affinity
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				network.SetProfile(a, b, Profile{PacketLoss: 100})
+			}
+		}
+	}
+	for i := 1; i < len(points); i++ {
+		z := Link{A: points[i-1], B: points[i], Profile: Profile{Latency: 10 * time.Millisecond}}
+		network.SetProfile(z.A, z.B, z.Profile)
+		chain = append(chain, z)
+	}
+	network.LoadTables(BuildTables(NewGraph(chain), ShortestPath{}, 0))
+
+	d := &Dialer{Network: network, From: ports[0]}
+	hops, err := d.TraceForward(context.Background(), ports[3], "/1k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) != 4 {
+		t.Fatalf("expected 4 hops, got %v", hops)
+	}
+	for i, hop := range hops {
+		if hop.Port != ports[i] {
+			t.Errorf("expected hop %v at %v, got %v", i, ports[i], hop.Port)
+		}
+	}
+
+	t.Run("No route", func(t *testing.T) {
+		d := &Dialer{Network: network, From: ports[3]}
+		res, err := d.Forward(context.Background(), ports[0], "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadGateway {
+			t.Errorf("expected status 502, got %v", res.StatusCode)
+		}
+	})
+
+	t.Run("Loop", func(t *testing.T) {
+		network.SetProfile(points[1], points[0], Profile{})
+		for i := 0; i < 2; i++ {
+			loop := ForwardingTable{Point: points[i], Entries: []Forward{{Destination: points[3], NextHop: points[1-i]}}}
+			if err := network.LoadTable(ports[i], loop); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res, err := d.Forward(context.Background(), ports[3], "/1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusLoopDetected {
+			t.Errorf("expected status 508, got %v", res.StatusCode)
+		}
+	})
+
+	t.Run("Load by HTTP", func(t *testing.T) {
+		table := ForwardingTable{Point: points[2], Entries: []Forward{{Destination: points[0], NextHop: points[1]}}}
+		b, _ := json.Marshal(table)
+		url := fmt.Sprintf("http://127.0.0.1:%d/table", ports[2])
+		req, _ := http.NewRequest("PUT", url, bytes.NewReader(b))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %v", res.StatusCode)
+		}
+
+		res, err = http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var loaded ForwardingTable
+		if err := json.NewDecoder(res.Body).Decode(&loaded); err != nil {
+			t.Fatal(err)
+		}
+		if f, ok := loaded.Lookup(points[0]); !ok || f.NextHop != points[1] {
+			t.Errorf("expected loaded next hop %v, got %+v", points[1], loaded)
+		}
+	})
+
+	t.Run("Unsorted", func(t *testing.T) {
+		dests := []Point{points[0], points[1], points[3]}
+		sort.Slice(dests, func(i, j int) bool { return lessPoint(dests[j], dests[i]) })
+		table := ForwardingTable{Point: points[2]}
+		for _, p := range dests {
+			table.Entries = append(table.Entries, Forward{Destination: p, NextHop: p})
+		}
+		b, _ := json.Marshal(table)
+		url := fmt.Sprintf("http://127.0.0.1:%d/table", ports[2])
+		req, _ := http.NewRequest("PUT", url, bytes.NewReader(b))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %v", res.StatusCode)
+		}
+
+		for _, i := range []int{0, 1, 3} {
+			if hops := network.nextHops(ports[2], ports[i]); len(hops) != 1 || hops[0] != ports[i] {
+				t.Errorf("expected next hop %v to %v, got %v", ports[i], ports[i], hops)
+			}
+		}
+	})
+}
@@ -0,0 +1,275 @@
+package simnet
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TrafficModel generates traffic matrices by the gravity model, where the
+// demand between two points is proportional to the product of their weights.
+type TrafficModel struct {
+	// Total is the total demand in kbit/s at the peak.
+	Total float64
+	// Population weights points by city names, e.g. populations in millions,
+	// which is required for every point of the matrix.
+	Population map[string]float64
+	// Tiers weights points by tiers, 2^(4-tier) if missing, so a core weighs
+	// 8 times as a city of the fourth tier.
+	Tiers map[int]float64
+	// Peak is the time of day of the busiest business hours.
+	Peak time.Duration
+	// Trough is the ratio of demand 12 hours off the peak to the peak.
+	Trough float64
+}
+
+// Weight returns the weight of point, which is zero if the population is
+// missing.
+func (m *TrafficModel) Weight(p Point) float64 {
+	w := m.Population[p.City.Name]
+	tier := Tier(p.City.Name)
+	if v, ok := m.Tiers[tier]; ok {
+		return w * v
+	}
+	return w * math.Pow(2, float64(4-tier))
+}
+
+// Ratio returns the ratio of demand at given time to the peak, which follows a
+// cosine curve over the day.
+func (m *TrafficModel) Ratio(t time.Time) float64 {
+	h, min, s := t.Clock()
+	day := time.Duration(h)*time.Hour + time.Duration(min)*time.Minute + time.Duration(s)*time.Second
+	x := (1 + math.Cos(2*math.Pi*float64(day-m.Peak)/float64(24*time.Hour))) / 2
+	return m.Trough + (1-m.Trough)*x
+}
+
+// Matrix returns the traffic matrix among points at given time, or an error
+// if the population of any point is missing.
+func (m *TrafficModel) Matrix(points []Point, t time.Time) (TrafficMatrix, error) {
+	for _, p := range points {
+		if _, ok := m.Population[p.City.Name]; !ok {
+			return nil, fmt.Errorf("missing population: %v", p.City.Name)
+		}
+	}
+
+	var sum float64
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				sum += m.Weight(a) * m.Weight(b)
+			}
+		}
+	}
+
+	tm := make(TrafficMatrix)
+	if sum == 0 {
+		return tm, nil
+	}
+	total := m.Total * m.Ratio(t)
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				tm[Pair{a, b}] = total * m.Weight(a) * m.Weight(b) / sum
+			}
+		}
+	}
+	return tm, nil
+}
+
+// Generator drives a measurer with downloads according to traffic matrices.
+type Generator struct {
+	// Measurer is used to download, e.g. a Network or an Engine.
+	Measurer Measurer
+	// Size is the downloading size in bytes of each download.
+	Size int
+	// Concurrency is the maximum number of concurrent downloads, 1 if
+	// non-positive. Downloads are delayed once all are busy.
+	Concurrency int
+	// Clock schedules downloads, RealClock if nil.
+	Clock Clock
+
+	mu    sync.Mutex
+	carry map[Pair]float64
+}
+
+// Drive downloads for the duration, where each pair downloads evenly spaced
+// at the rate of its demand, it returns results of all pairs downloaded.
+//
+// Demands rarely make whole downloads, so the fraction of downloads left by
+// each pair is carried to its next drive, thus the bytes downloaded by every
+// pair match the matrix over drives even if it demands less than a download
+// in the duration.
+func (g *Generator) Drive(ctx context.Context, m TrafficMatrix, d time.Duration) (Matrix, error) {
+	if g.Size <= 0 {
+		return nil, fmt.Errorf("invalid size: %v", g.Size)
+	}
+	clock, concurrency := g.Clock, g.Concurrency
+	if clock == nil {
+		clock = RealClock
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type download struct {
+		at   time.Duration
+		pair Pair
+	}
+	var pairs []Pair
+	for k := range m {
+		pairs = append(pairs, k)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return lessPair(pairs[i], pairs[j]) })
+
+	var schedule []download
+	g.mu.Lock()
+	if g.carry == nil {
+		g.carry = make(map[Pair]float64)
+	}
+	for _, k := range pairs {
+		x := g.carry[k] + m[k]*1000/8/float64(g.Size)*d.Seconds()
+		n := int(x)
+		g.carry[k] = x - float64(n)
+		for i := 0; i < n; i++ {
+			schedule = append(schedule, download{d * time.Duration(i) / time.Duration(n), k})
+		}
+	}
+	g.mu.Unlock()
+	sort.SliceStable(schedule, func(i, j int) bool { return schedule[i].at < schedule[j].at })
+
+	results := make(Matrix)
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		jobs = make(chan Pair)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				s, err := g.Measurer.Measure(ctx, k.A, k.B, g.Size)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				if results[k] == nil {
+					results[k] = &Result{A: k.A, B: k.B}
+				}
+				results[k].add(s, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	since := clock.Now()
+feed:
+	for _, v := range schedule {
+		select {
+		case <-clock.After(v.at - clock.Now().Sub(since)):
+		case <-ctx.Done():
+			break feed
+		}
+		select {
+		case jobs <- v.pair:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// trafficColumns are required columns of ReadTrafficMatrix.
+var trafficColumns = []string{"city_a", "isp_a", "city_b", "isp_b", "kbps"}
+
+// ReadTrafficMatrix reads a recorded traffic matrix from CSV. The first row
+// is a header containing the columns below in any order.
+//
+//	city_a, isp_a   - the source point
+//	city_b, isp_b   - the destination point
+//	kbps            - the demand in kbit/s
+func ReadTrafficMatrix(r io.Reader) (TrafficMatrix, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	index := make(map[string]int)
+	for i, name := range records[0] {
+		index[name] = i
+	}
+	for _, name := range trafficColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing column: %v", name)
+		}
+	}
+
+	m := make(TrafficMatrix)
+	for i, record := range records[1:] {
+		line := i + 2
+		field := func(name string) string { return record[index[name]] }
+		number := func(name string) float64 {
+			if err != nil {
+				return 0
+			}
+			var v float64
+			v, err = strconv.ParseFloat(field(name), 64)
+			if err != nil {
+				err = fmt.Errorf("line %v: invalid %v: %v", line, name, err)
+			}
+			return v
+		}
+
+		a, ok := cities[field("city_a")]
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown city: %v", line, field("city_a"))
+		}
+		b, ok := cities[field("city_b")]
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown city: %v", line, field("city_b"))
+		}
+
+		k := Pair{Point{a, ISP(number("isp_a"))}, Point{b, ISP(number("isp_b"))}}
+		demand := number("kbps")
+		if err != nil {
+			return nil, err
+		}
+		m[k] += demand
+	}
+	return m, nil
+}
+
+// WriteTrafficMatrix writes the traffic matrix as CSV readable by
+// ReadTrafficMatrix, in order of points.
+func WriteTrafficMatrix(w io.Writer, m TrafficMatrix) error {
+	var pairs []Pair
+	for k := range m {
+		pairs = append(pairs, k)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return lessPair(pairs[i], pairs[j]) })
+
+	cw := csv.NewWriter(w)
+	cw.Write(trafficColumns)
+	for _, k := range pairs {
+		cw.Write([]string{
+			k.A.City.Name, strconv.FormatFloat(float64(k.A.ISP), 'g', -1, 64),
+			k.B.City.Name, strconv.FormatFloat(float64(k.B.ISP), 'g', -1, 64),
+			strconv.FormatFloat(m[k], 'g', -1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
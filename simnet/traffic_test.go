@@ -0,0 +1,171 @@
+package simnet
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrafficModel(t *testing.T) {
+	core := Point{City: cities["上海市"], ISP: 1}
+	capital := Point{City: cities["杭州市"], ISP: 1}
+	city := Point{City: cities["宁波市"], ISP: 1}
+	m := &TrafficModel{
+		Total:      1000,
+		Population: map[string]float64{"上海市": 1, "杭州市": 1, "宁波市": 4},
+		Peak:       14 * time.Hour,
+		Trough:     0.2,
+	}
+
+	if w := m.Weight(core); w != 8 {
+		t.Errorf("expected core weighing 8, got %v", w)
+	}
+	if w := m.Weight(city); w != 4 {
+		t.Errorf("expected city weighing 4 by population, got %v", w)
+	}
+	peak := time.Date(2017, 1, 1, 14, 0, 0, 0, time.UTC)
+	if r := m.Ratio(peak); r != 1 {
+		t.Errorf("expected ratio 1 at peak, got %v", r)
+	}
+	if r := m.Ratio(peak.Add(12 * time.Hour)); math.Abs(r-0.2) > 1e-9 {
+		t.Errorf("expected ratio 0.2 at night, got %v", r)
+	}
+
+	tm, err := m.Matrix([]Point{core, capital, city}, peak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, v := range tm {
+		sum += v
+	}
+	if len(tm) != 6 || math.Abs(sum-1000) > 1e-9 {
+		t.Errorf("expected 6 demands of 1000 kbit/s in total, got %v of %v", len(tm), sum)
+	}
+	if tm[Pair{core, city}] != 4*tm[Pair{capital, city}] {
+		t.Errorf("expected demands proportional to weights, got %v", tm)
+	}
+	if _, err := (&TrafficModel{Total: 1000}).Matrix([]Point{core, city}, peak); err == nil {
+		t.Errorf("expected an error of missing population")
+	}
+}
+
+func TestTrafficMatrixCSV(t *testing.T) {
+	a := Point{City: cities["上海市"], ISP: 1}
+	b := Point{City: cities["杭州市"], ISP: 2.5}
+	m := TrafficMatrix{{a, b}: 100, {b, a}: 12.5}
+
+	var buf bytes.Buffer
+	if err := WriteTrafficMatrix(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ReadTrafficMatrix(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 2 || replayed[Pair{a, b}] != 100 || replayed[Pair{b, a}] != 12.5 {
+		t.Errorf("expected %v, got %v", m, replayed)
+	}
+
+	for _, s := range []string{
+		"city_a,isp_a,city_b,isp_b\n",
+		"city_a,isp_a,city_b,isp_b,kbps\n上海市,1,杭州市,1,x\n",
+		"city_a,isp_a,city_b,isp_b,kbps\n上海市,1,nowhere,1,1\n",
+	} {
+		if _, err := ReadTrafficMatrix(strings.NewReader(s)); err == nil {
+			t.Errorf("expected an error reading %q", s)
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	a := Point{City: cities["上海市"], ISP: 1}
+	b := Point{City: cities["杭州市"], ISP: 1}
+	graph := Affinity{
+		{A: a, B: b, Profile: Profile{Latency: 10 * time.Millisecond}},
+		{A: b, B: a, Profile: Profile{PacketLoss: 100}},
+	}
+	g := &Generator{Measurer: NewEngine(graph, 1), Size: 1000, Concurrency: 4}
+
+	since := time.Now()
+	m, err := g.Drive(context.Background(), TrafficMatrix{{a, b}: 160, {b, a}: 80}, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(since); elapsed < 400*time.Millisecond {
+		t.Errorf("expected downloads spread over 500ms, got %v", elapsed)
+	}
+	if r := m[Pair{a, b}]; r == nil || r.Samples != 10 || r.Failures != 0 {
+		t.Errorf("expected 10 downloads from %v to %v, got %+v", a, b, r)
+	}
+	if r := m[Pair{b, a}]; r == nil || r.Samples != 5 || r.Failures != 5 {
+		t.Errorf("expected 5 failed downloads from %v to %v, got %+v", b, a, r)
+	}
+	if _, err := (&Generator{Measurer: g.Measurer}).Drive(context.Background(), nil, time.Second); err == nil {
+		t.Errorf("expected an error of zero size")
+	}
+}
+
+func TestGeneratorFractions(t *testing.T) {
+	// points are of equal populations, so that only tiers weigh
+	var points []Point
+	model := &TrafficModel{Total: 8000, Population: make(map[string]float64), Peak: 14 * time.Hour}
+	for _, name := range names {
+		if Tier(name) <= 3 {
+			points = append(points, Point{City: cities[name], ISP: 1})
+			model.Population[name] = 1
+		}
+	}
+	tm, err := model.Matrix(points, time.Date(2017, 1, 1, 14, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100 downloads a second are spread over all pairs, so most pairs demand
+	// less than a download
+	g := &Generator{
+		Measurer:    NewEngine(NewAffinity(points), 1),
+		Size:        10000,
+		Concurrency: 4,
+		Clock:       NewScaledClock(100),
+	}
+	counts := make(map[Pair]int)
+	for i := 0; i < 3; i++ {
+		m, err := g.Drive(context.Background(), tm, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, r := range m {
+			counts[k] += r.Samples
+		}
+		for k, v := range tm {
+			want := v * 1000 / 8 / float64(g.Size) * float64(i+1)
+			if n := float64(counts[k]); n > want+1e-9 || n <= want-1 {
+				t.Errorf("expected %.2f downloads from %v to %v in %v drives, got %v", want, k.A, k.B, i+1, n)
+			}
+		}
+	}
+
+	t.Run("Even pairs", func(t *testing.T) {
+		a, b := points[0], points[1]
+		// each pair demands 0.6 downloads a drive
+		tm := TrafficMatrix{{a, b}: 48, {b, a}: 48}
+		g := &Generator{Measurer: g.Measurer, Size: g.Size, Clock: NewScaledClock(100)}
+		counts := make(map[Pair]int)
+		for i := 0; i < 6; i++ {
+			m, err := g.Drive(context.Background(), tm, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, r := range m {
+				counts[k] += r.Samples
+			}
+		}
+		if counts[Pair{a, b}] != 3 || counts[Pair{b, a}] != 3 {
+			t.Errorf("expected 3 downloads of each pair, got %v", counts)
+		}
+	})
+}
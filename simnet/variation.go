@@ -0,0 +1,130 @@
+package simnet
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// Variation varies the profile of link from a to b by time.
+type Variation interface {
+	Vary(t time.Time, a, b Point, p Profile) Profile
+}
+
+// Diurnal is a daily congestion between two ISPs, the congestion follows a
+// sinusoid reaching the Profile at Peak and vanishing 12 hours apart.
+type Diurnal struct {
+	// A and B is the pair of congested ISPs, a zero side matches every ISP,
+	// e.g. only A set means A against all other ISPs.
+	A, B ISP
+	// Peak is the time of day when the congestion reaches the top.
+	Peak time.Duration
+
+	Profile
+}
+
+// Vary implements the Variation interface.
+func (d Diurnal) Vary(t time.Time, a, b Point, p Profile) Profile {
+	if a.ISP == b.ISP {
+		return p
+	}
+	match := func(x, y ISP) bool {
+		return (d.A == 0 || x == d.A) && (d.B == 0 || y == d.B)
+	}
+	if !match(a.ISP, b.ISP) && !match(b.ISP, a.ISP) {
+		return p
+	}
+
+	h, m, s := t.Clock()
+	day := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	x := (1 + math.Cos(2*math.Pi*float64(day-d.Peak)/float64(24*time.Hour))) / 2
+	return degrade(p, d.Profile, x)
+}
+
+// DefaultSlot is the slot of Bursty and Brownout if their Slot is not positive.
+const DefaultSlot = time.Minute
+
+// Bursty is a bursty packet loss on every link. The time is divided into
+// slots, each slot of a link is lossy by the Probability independently, so
+// consecutive lossy slots form a burst.
+type Bursty struct {
+	// Slot defaults to DefaultSlot if not positive.
+	Slot        time.Duration
+	Probability float64
+	PacketLoss  int
+	Seed        int64
+}
+
+// Vary implements the Variation interface.
+func (b Bursty) Vary(t time.Time, x, y Point, p Profile) Profile {
+	if chance(b.Seed, slotOf(t, b.Slot), x, y) < b.Probability {
+		return degrade(p, Profile{PacketLoss: b.PacketLoss}, 1)
+	}
+	return p
+}
+
+// Brownout is a random brownout of points. The time is divided into slots,
+// a point is brown out in a slot by the Probability, then all links from or
+// to the point are degraded by the Profile.
+type Brownout struct {
+	// Slot defaults to DefaultSlot if not positive.
+	Slot        time.Duration
+	Probability float64
+	Seed        int64
+
+	Profile
+}
+
+// Vary implements the Variation interface.
+func (b Brownout) Vary(t time.Time, x, y Point, p Profile) Profile {
+	slot := slotOf(t, b.Slot)
+	if chance(b.Seed, slot, x) < b.Probability || chance(b.Seed, slot, y) < b.Probability {
+		return degrade(p, b.Profile, 1)
+	}
+	return p
+}
+
+// Vary returns the affinity varied at time t.
+func (graph Affinity) Vary(t time.Time, vs ...Variation) Affinity {
+	r := make(Affinity, len(graph))
+	for i, z := range graph {
+		for _, v := range vs {
+			z.Profile = v.Vary(t, z.A, z.B, z.Profile)
+		}
+		r[i] = z
+	}
+	return r
+}
+
+// slotOf returns the index of slot containing time t.
+func slotOf(t time.Time, slot time.Duration) int64 {
+	if slot <= 0 {
+		slot = DefaultSlot
+	}
+	return t.UnixNano() / int64(slot)
+}
+
+// degrade adds the packet loss and latency of d scaled by x to p, and limits
+// the bandwidth of p to d if the x is positive.
+func degrade(p, d Profile, x float64) Profile {
+	p.PacketLoss += int(math.Round(float64(d.PacketLoss) * x))
+	if p.PacketLoss > 100 {
+		p.PacketLoss = 100
+	}
+	p.Latency += time.Duration(float64(d.Latency) * x)
+	if x > 0 && d.Bandwidth > 0 && (p.Bandwidth == 0 || d.Bandwidth < p.Bandwidth) {
+		p.Bandwidth = d.Bandwidth
+	}
+	return p
+}
+
+// chance returns a deterministic random number in [0, 1) of given values.
+func chance(seed, slot int64, points ...Point) float64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%d", seed, slot)
+	for _, p := range points {
+		fmt.Fprintf(h, "/%d:%v", p.City.ID, p.ISP)
+	}
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
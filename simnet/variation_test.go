@@ -0,0 +1,92 @@
+package simnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiurnal(t *testing.T) {
+	a := Point{City: cities["北京市"], ISP: 1}
+	b := Point{City: cities["上海市"], ISP: 2}
+	c := Point{City: cities["上海市"], ISP: 1}
+	d := Diurnal{Peak: 21 * time.Hour, Profile: Profile{PacketLoss: 40, Latency: 100 * time.Millisecond}}
+
+	day := time.Date(2017, 10, 23, 0, 0, 0, 0, time.UTC)
+	peak := d.Vary(day.Add(21*time.Hour), a, b, Profile{})
+	if peak.PacketLoss != 40 || peak.Latency != 100*time.Millisecond {
+		t.Errorf("expected the peak %+v, got %+v", d.Profile, peak)
+	}
+	if p := d.Vary(day.Add(9*time.Hour), a, b, Profile{}); p != (Profile{}) {
+		t.Errorf("expected no congestion, got %+v", p)
+	}
+	if p := d.Vary(day.Add(21*time.Hour), a, c, Profile{}); p != (Profile{}) {
+		t.Errorf("expected no congestion within same ISP, got %+v", p)
+	}
+
+	d.A, d.B = 2, 3
+	if p := d.Vary(day.Add(21*time.Hour), a, b, Profile{}); p != (Profile{}) {
+		t.Errorf("expected no congestion between other ISPs, got %+v", p)
+	}
+
+	d.A, d.B = 2, 0
+	if p := d.Vary(day.Add(21*time.Hour), a, b, Profile{}); p.PacketLoss != 40 {
+		t.Errorf("expected ISP 2 congested against all ISPs, got %+v", p)
+	}
+	d.A, d.B = 0, 3
+	if p := d.Vary(day.Add(21*time.Hour), a, b, Profile{}); p != (Profile{}) {
+		t.Errorf("expected no congestion without ISP 3, got %+v", p)
+	}
+}
+
+func TestBursty(t *testing.T) {
+	a := Point{City: cities["北京市"], ISP: 1}
+	b := Point{City: cities["上海市"], ISP: 2}
+	v := Bursty{Slot: time.Second, Probability: 0.3, PacketLoss: 50}
+
+	since := time.Date(2017, 10, 23, 0, 0, 0, 0, time.UTC)
+	lossy := 0
+	for i := 0; i < 1000; i++ {
+		now := since.Add(time.Duration(i) * time.Second)
+		p := v.Vary(now, a, b, Profile{})
+		if p != v.Vary(now.Add(time.Second/2), a, b, Profile{}) {
+			t.Fatal("expected same profile within a slot")
+		}
+		if p.PacketLoss == 50 {
+			lossy++
+		}
+	}
+	if lossy < 200 || lossy > 400 {
+		t.Errorf("expected about 300 lossy slots, got %v", lossy)
+	}
+
+	zero := Bursty{Probability: 1, PacketLoss: 50}
+	if p := zero.Vary(since, a, b, Profile{}); p.PacketLoss != 50 {
+		t.Errorf("expected 50%% packet loss with default slot, got %v", p.PacketLoss)
+	}
+	if p := (Brownout{}).Vary(since, a, b, Profile{}); p != (Profile{}) {
+		t.Errorf("expected unchanged profile, got %v", p)
+	}
+}
+
+func TestBrownout(t *testing.T) {
+	var points []Point
+	for i := 0; i < len(names); i++ {
+		points = append(points, NewPoint(i))
+	}
+	v := Brownout{Slot: time.Minute, Probability: 0.1, Profile: Profile{Latency: time.Second}}
+	now := time.Date(2017, 10, 23, 0, 0, 0, 0, time.UTC)
+
+	browned := make(map[Point]bool)
+	for _, z := range NewAffinity(points).Vary(now, v) {
+		if z.Latency >= time.Second {
+			if chance(v.Seed, now.UnixNano()/int64(v.Slot), z.A) >= v.Probability {
+				browned[z.B] = true
+			} else {
+				browned[z.A] = true
+			}
+		}
+	}
+	if len(browned) == 0 || len(browned) > len(points)/5 {
+		t.Errorf("expected about %v browned out points, got %v", len(points)/10, len(browned))
+	}
+}
@@ -0,0 +1,193 @@
+package simnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// vivaldiCe is the tuning factor of error.
+	vivaldiCe = 0.25
+	// vivaldiCc is the tuning factor of timestep.
+	vivaldiCc = 0.25
+	// vivaldiMinHeight is the minimum height in seconds.
+	vivaldiMinHeight = 10e-6
+)
+
+// Coordinate is a Vivaldi network coordinate, which is a 2D Euclidean
+// position plus a height modelling the access link. All values are in
+// seconds of round-trip time.
+type Coordinate struct {
+	X, Y   float64
+	Height float64
+	// Error is the estimated relative error of the coordinate.
+	Error float64
+}
+
+// Distance returns the predicted round-trip time between two coordinates.
+func (c Coordinate) Distance(o Coordinate) time.Duration {
+	return time.Duration(c.distance(o) * float64(time.Second))
+}
+
+func (c Coordinate) distance(o Coordinate) float64 {
+	return math.Hypot(c.X-o.X, c.Y-o.Y) + c.Height + o.Height
+}
+
+// Vivaldi learns a coordinate from measured round-trip times to others.
+type Vivaldi struct {
+	mu    sync.Mutex
+	coord Coordinate
+	rand  *rand.Rand
+}
+
+// NewVivaldi creates a coordinate at origin with the maximum error, the seed
+// is used to push apart coordinates at the same position.
+func NewVivaldi(seed int64) *Vivaldi {
+	return &Vivaldi{
+		coord: Coordinate{Height: vivaldiMinHeight, Error: 1},
+		rand:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Coordinate returns the current coordinate.
+func (v *Vivaldi) Coordinate() Coordinate {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.coord
+}
+
+// Update moves the coordinate by a measured round-trip time to the remote.
+func (v *Vivaldi) Update(rtt time.Duration, remote Coordinate) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c := &v.coord
+	sample := rtt.Seconds()
+	if sample <= 0 {
+		return
+	}
+
+	dist := c.distance(remote)
+	w := c.Error / (c.Error + remote.Error)
+	es := math.Abs(dist-sample) / sample
+	c.Error = math.Min(es*vivaldiCe*w+c.Error*(1-vivaldiCe*w), 1)
+
+	force := vivaldiCc * w * (sample - dist)
+	dx, dy := c.X-remote.X, c.Y-remote.Y
+	norm := math.Hypot(dx, dy)
+	if norm == 0 {
+		// pushes away in a random direction if at the same position
+		angle := v.rand.Float64() * 2 * math.Pi
+		dx, dy, norm = math.Cos(angle), math.Sin(angle), 1
+	}
+	c.X += force * dx / norm
+	c.Y += force * dy / norm
+	if dist > 0 {
+		c.Height = math.Max(c.Height+force*(c.Height+remote.Height)/dist, vivaldiMinHeight)
+	}
+}
+
+// LearnCoordinates learns coordinates of points by measuring round-trip
+// times. In each round every point measures a random peer and updates its
+// coordinate, unreachable peers are ignored.
+func LearnCoordinates(ctx context.Context, m Measurer, points []Point, rounds int, seed int64) (map[Point]Coordinate, error) {
+	r := rand.New(rand.NewSource(seed))
+	nodes := make(map[Point]*Vivaldi, len(points))
+	for _, p := range points {
+		nodes[p] = NewVivaldi(r.Int63())
+	}
+
+	for i := 0; i < rounds && len(points) > 1; i++ {
+		for _, a := range points {
+			b := points[r.Intn(len(points))]
+			if a == b {
+				continue
+			}
+
+			s, err := m.Measure(ctx, a, b, 0)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err == nil {
+				nodes[a].Update(s.FirstByte-s.Connect, nodes[b].Coordinate())
+			}
+		}
+	}
+
+	coords := make(map[Point]Coordinate, len(nodes))
+	for p, v := range nodes {
+		coords[p] = v.Coordinate()
+	}
+	return coords, nil
+}
+
+// Coordinate returns the coordinate of the first server of given point.
+func (n *Network) Coordinate(p Point) (Coordinate, bool) {
+	port, ok := n.port(p)
+	if !ok {
+		return Coordinate{}, false
+	}
+	return n.coordinate(port).Coordinate(), true
+}
+
+func (n *Network) coordinate(port int) *Vivaldi {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.coords[port]
+}
+
+// ExchangeCoordinate makes the server of a fetch the coordinate from the
+// server of b, then updates its coordinate by the round-trip time excluding
+// the connecting time.
+func (n *Network) ExchangeCoordinate(ctx context.Context, a, b Point) error {
+	from, ok := n.port(a)
+	if !ok {
+		return fmt.Errorf("no server of %v", a)
+	}
+	to, ok := n.port(b)
+	if !ok {
+		return fmt.Errorf("no server of %v", b)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/coordinate", to), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(FromHeader, fmt.Sprint(from))
+
+	var connect, firstByte time.Duration
+	clock := n.Clock()
+	since := clock.Now()
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			connect = clock.Now().Sub(since)
+		},
+		GotFirstResponseByte: func() {
+			firstByte = clock.Now().Sub(since)
+		},
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	var remote Coordinate
+	if err := json.NewDecoder(res.Body).Decode(&remote); err != nil {
+		return err
+	}
+	n.coordinate(from).Update(firstByte-connect, remote)
+	return nil
+}
@@ -0,0 +1,81 @@
+package simnet
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLearnCoordinates(t *testing.T) {
+	var points []Point
+	for _, name := range names {
+		if Tier(name) < 4 {
+			points = append(points, NewPointFromCity(name))
+		}
+	}
+
+	// latency is proportional to the geographical distance
+	var graph Affinity
+	for _, a := range points {
+		for _, b := range points {
+			if a != b {
+				x, _ := GetLocation(a.City.Name)
+				y, _ := GetLocation(b.City.Name)
+				d := math.Hypot(x.Longitude-y.Longitude, x.Latitude-y.Latitude)
+				graph = append(graph, Link{A: a, B: b, Profile: Profile{Latency: time.Duration(d * float64(time.Millisecond))}})
+			}
+		}
+	}
+
+	coords, err := LearnCoordinates(context.Background(), NewEngine(graph, 1), points, 200, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := LearnCoordinates(context.Background(), NewEngine(graph, 1), points, 200, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(coords, again) {
+		t.Error("expected same coordinates by same seed")
+	}
+
+	var errs []float64
+	for _, z := range graph {
+		predicted := coords[z.A].Distance(coords[z.B])
+		errs = append(errs, math.Abs(float64(predicted-z.Latency))/float64(z.Latency))
+	}
+	sort.Float64s(errs)
+	if median := errs[len(errs)/2]; median > 0.2 {
+		t.Errorf("expected median relative error <= 0.2, got %v", median)
+	}
+}
+
+func TestExchangeCoordinate(t *testing.T) {
+	network, err := NewNetwork(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	points := network.Points()
+	a, b := points[0], points[1]
+	network.SetProfile(a, b, Profile{Latency: 20 * time.Millisecond})
+	network.SetProfile(b, a, Profile{Latency: 20 * time.Millisecond})
+	for i := 0; i < 30; i++ {
+		if err := network.ExchangeCoordinate(context.Background(), a, b); err != nil {
+			t.Fatal(err)
+		}
+		if err := network.ExchangeCoordinate(context.Background(), b, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	x, _ := network.Coordinate(a)
+	y, _ := network.Coordinate(b)
+	if d := x.Distance(y); d < 15*time.Millisecond || d > 30*time.Millisecond {
+		t.Errorf("expected predicted latency about 20ms, got %v", d)
+	}
+}